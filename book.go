@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/cognito"
+	"github.com/eshaanm25/corepower/internal/config"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/history"
+	"github.com/eshaanm25/corepower/internal/logging"
+	"github.com/eshaanm25/corepower/internal/metrics"
+	"github.com/eshaanm25/corepower/internal/notify"
+	"github.com/eshaanm25/corepower/internal/opensearch"
+	"github.com/eshaanm25/corepower/internal/output"
+)
+
+// runBook searches for classes matching the preferences and books the best one
+func runBook(ctx context.Context, args []string) {
+	// Define command line flags
+	flags := flag.NewFlagSet("book", flag.ExitOnError)
+	username := flags.String("username", "", "CorePower username")
+	password := flags.String("password", "", "CorePower password")
+	maxAttempts := flags.Int("max-attempts", 3, "Maximum number of classes to try booking")
+	configPath := flags.String("config", "", "Path to a JSON config file")
+	historyPath := flags.String("history", defaultHistoryPath(), "File to record runs in, empty to disable")
+	outputFlag := flags.String("output", "", "Print the run as table, json, yaml or csv")
+	flags.Parse(args)
+
+	if *username == "" || *password == "" {
+		exit(exitUsage, "Both -username and -password flags are required")
+	}
+	if *maxAttempts < 1 {
+		exit(exitUsage, "The -max-attempts flag must be at least 1")
+	}
+	var format output.Format
+	if *outputFlag != "" {
+		var err error
+		if format, err = output.Parse(*outputFlag); err != nil {
+			exit(exitUsage, "Invalid -output", "error", err)
+		}
+	}
+
+	b, err := newBooker(*configPath, *username, *password)
+	if err != nil {
+		exit(exitConfig, "Error loading config", "error", err)
+	}
+	b.maxAttempts = *maxAttempts
+	b.history = openHistory(*historyPath)
+
+	run, err := b.book(ctx, time.Time{})
+	if format != "" {
+		if err := output.Write(os.Stdout, format, run, runRows([]history.Run{run})); err != nil {
+			fatal("Error writing output", "error", err)
+		}
+	}
+	if err != nil {
+		fatal("Booking run failed", "error", err)
+	}
+	os.Exit(outcomeCode(run.Outcome))
+}
+
+// booker makes booking runs for one account. The search client and token
+// manager are kept between runs.
+type booker struct {
+	cfg          *config.Config
+	username     string
+	maxAttempts  int
+	history      *history.Store // Nil to not record runs
+	clk          clock.Clock
+	notifier     notify.Notifier
+	searchClient *opensearch.Client
+	ctm          *cognito.CognitoTokenManager
+}
+
+func newBooker(configPath, username, password string) (*booker, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	notifier, err := notify.New(cfg.Notify)
+	if err != nil {
+		return nil, err
+	}
+
+	searchClient := &opensearch.Client{}
+	searchClient.Initialize()
+
+	return &booker{
+		cfg:          cfg,
+		username:     username,
+		maxAttempts:  3,
+		clk:          clock.System,
+		notifier:     notifier,
+		searchClient: searchClient,
+		ctm:          cognito.NewCognitoTokenManager(username, password),
+	}, nil
+}
+
+// plan searches the booking horizon and ranks the classes matching the
+// preferences, best first, without booking any
+func (b *booker) plan(ctx context.Context) (*history.Search, []corepower.Result, error) {
+	// Get start and end times from the booking horizon
+	profile := b.cfg.Profile
+	now := b.clk.Now()
+	startTime, endTime := profile.Horizon.Window(now)
+	if err := profile.LoadCalendars(startTime.Add(-24*time.Hour), endTime.Add(24*time.Hour)); err != nil {
+		return nil, nil, &configError{fmt.Errorf("error loading calendars: %v", err)}
+	}
+	if len(profile.Calendars) > 0 {
+		slog.InfoContext(ctx, "Loaded calendars", "busy_periods", len(profile.Busy))
+	}
+
+	// Get center IDs
+	centerIds, err := resolveCenters(ctx, b.searchClient, b.cfg.CenterRefs())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error resolving centers: %w", err)
+	}
+
+	// Call the search function
+	slog.InfoContext(ctx, "Searching for available classes", "from", startTime, "to", endTime, "centers", len(centerIds))
+	res, err := b.searchClient.Find(ctx, profile.Query(startTime, endTime, centerIds))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error during search: %w", err)
+	}
+	slog.InfoContext(ctx, "Found classes", "results", len(res.Results))
+	search := &history.Search{From: startTime, To: endTime, Centers: centerIds, Results: len(res.Results)}
+
+	// Rank classes based on preferences
+	var scorers []corepower.Scorer
+	if b.cfg.Predict != nil {
+		predictor, err := loadPredictor(*b.cfg.Predict)
+		if err != nil {
+			return search, nil, fmt.Errorf("error loading snapshots: %v", err)
+		}
+		scorers = append(scorers, predictor)
+	}
+	candidates := corepower.RankClasses(res, profile, b.clk, scorers...)
+	search.Candidates = len(candidates)
+	return search, candidates, nil
+}
+
+// book runs one search and walks down the ranked classes until one is booked.
+// opened is when the booking window opened for runs started on a schedule,
+// zero otherwise. The run is returned as recorded in the history, along with
+// the error that stopped it, or the last error when none of the classes tried
+// could be booked. Errors are notified and recorded before returning.
+func (b *booker) book(ctx context.Context, opened time.Time) (history.Run, error) {
+	clk := b.clk
+
+	// run is recorded in the history store however the run ends, its ID
+	// tags the run's log records
+	run := history.Run{ID: history.NewRunID(clk.Now()), Time: clk.Now(), User: b.username}
+	ctx = logging.WithRunID(ctx, run.ID)
+	record := func(outcome history.Outcome, err error) {
+		run.Outcome = outcome
+		if err != nil {
+			run.Error = err.Error()
+		}
+		if b.history == nil {
+			return
+		}
+		if err := b.history.Append(run); err != nil {
+			slog.ErrorContext(ctx, "Error recording run", "error", err)
+		}
+	}
+
+	// send notifies about the outcome of the run, failures to deliver are
+	// only logged
+	send := func(event notify.Event) {
+		event.Time = clk.Now()
+		event.User = b.username
+		if err := b.notifier.Notify(context.WithoutCancel(ctx), event); err != nil {
+			slog.ErrorContext(ctx, "Error sending notification", "error", err)
+		}
+	}
+
+	// fail notifies about a failure that stops the run
+	fail := func(err error) (history.Run, error) {
+		send(notify.Event{Type: notify.Failed, Error: err.Error()})
+		record(history.Failed, err)
+		return run, err
+	}
+
+	// Search For Classes
+	search, candidates, err := b.plan(ctx)
+	run.Search = search
+	if err != nil {
+		return fail(err)
+	}
+	if len(candidates) == 0 {
+		slog.InfoContext(ctx, "No ideal class found matching preferences")
+		send(notify.Event{Type: notify.NoMatch})
+		record(history.NoMatch, nil)
+		return run, nil
+	}
+
+	// Print ideal class details
+	idealClass := candidates[0]
+	slog.InfoContext(ctx, "Found ideal class",
+		"class", idealClass.ClassCategoryName,
+		"center", idealClass.CenterName,
+		"start", idealClass.StartTime.Format("Mon Jan 2 3:04 PM MST"),
+		"available_slots", idealClass.AvailableSlots,
+		"score", idealClass.Score,
+		"fallbacks", len(candidates)-1)
+
+	// Reserve a Class
+
+	// Authenticate, reusing the token of an earlier run while it is valid
+	token, err := b.ctm.Token(ctx)
+	if err != nil {
+		return fail(fmt.Errorf("error authenticating with CorePower API: %w", err))
+	}
+	slog.InfoContext(ctx, "Authenticated with CorePower API")
+
+	// Initialize the Reservations Client
+	corePowerClient := &corepower.Client{
+		Token: token,
+	}
+	corePowerClient.Initialize()
+
+	// Walk down the ranked classes until one is booked
+	var attempts []string
+	var lastErr error
+	full := 0
+	done := false
+	outcome := history.Failed
+	for i, class := range candidates {
+		if i >= b.maxAttempts {
+			break
+		}
+
+		classTime := class.StartTime.Format("Mon Jan 2 3:04 PM MST")
+		slog.InfoContext(ctx, "Sending reservation request",
+			"class", class.ClassCategoryName,
+			"center", class.CenterName,
+			"start", classTime)
+		if i == 0 && !opened.IsZero() {
+			metrics.SnipeReaction.Observe(clk.Now().Sub(opened).Seconds())
+		}
+		response, err := corePowerClient.Reserve(ctx, class.CenterID, class.SessionID)
+		if errors.Is(err, corepower.ErrAlreadyReserved) {
+			metrics.ReservationAttempts.Inc("already_reserved")
+			slog.InfoContext(ctx, "Class is already reserved, nothing to do")
+			attempts = append(attempts, fmt.Sprintf("%s at %s in %s: already reserved", class.ClassCategoryName, classTime, class.CenterName))
+			run.Attempts = append(run.Attempts, history.NewAttempt(class, history.AlreadyReserved, nil))
+			run.Class = &class
+			outcome = history.AlreadyReserved
+			done = true
+			break
+		} else if errors.Is(err, corepower.ErrClassFull) || errors.Is(err, corepower.ErrNotBookable) {
+			// Recoverable, try the next best class
+			if errors.Is(err, corepower.ErrClassFull) {
+				metrics.ReservationAttempts.Inc("full")
+				full++
+			} else {
+				metrics.ReservationAttempts.Inc("not_bookable")
+			}
+			slog.WarnContext(ctx, "Could not book class", "error", err)
+			attempts = append(attempts, fmt.Sprintf("%s at %s in %s: %v", class.ClassCategoryName, classTime, class.CenterName, err))
+			run.Attempts = append(run.Attempts, history.NewAttempt(class, history.Failed, err))
+			run.Class = &class
+			lastErr = err
+			continue
+		} else if err != nil {
+			metrics.ReservationAttempts.Inc("error")
+			send(notify.Event{Type: notify.Failed, Class: &class, Candidates: candidates, Error: err.Error()})
+			run.Attempts = append(run.Attempts, history.NewAttempt(class, history.Failed, err))
+			run.Class = &class
+			record(history.Failed, err)
+			return run, fmt.Errorf("error reserving: %w", err)
+		}
+
+		outcome = history.Booked
+		if response.Waitlisted() {
+			outcome = history.Waitlisted
+			slog.InfoContext(ctx, "Waitlisted", "class", response.ClassName, "center", response.Center, "start_utc", response.StartTimeUTC, "reservation_id", response.ID)
+			attempts = append(attempts, fmt.Sprintf("%s at %s in %s: waitlisted", class.ClassCategoryName, classTime, class.CenterName))
+			send(notify.Event{Type: notify.Waitlisted, Class: &class, Reservation: &response, Candidates: candidates})
+		} else {
+			slog.InfoContext(ctx, "Booked", "class", response.ClassName, "center", response.Center, "start_utc", response.StartTimeUTC, "reservation_id", response.ID)
+			attempts = append(attempts, fmt.Sprintf("%s at %s in %s: booked", class.ClassCategoryName, classTime, class.CenterName))
+			send(notify.Event{Type: notify.Booked, Class: &class, Reservation: &response, Candidates: candidates})
+		}
+		metrics.ReservationAttempts.Inc(string(outcome))
+		run.Attempts = append(run.Attempts, history.NewAttempt(class, outcome, nil))
+		run.Class = &class
+		run.Reservation = &response
+		done = true
+		break
+	}
+	var runErr error
+	if !done && lastErr != nil {
+		runErr = noBookingError(len(attempts), full, lastErr)
+		send(notify.Event{Type: notify.Failed, Candidates: candidates, Error: runErr.Error()})
+		record(history.Failed, runErr)
+	} else {
+		record(outcome, nil)
+	}
+
+	// Summarize what was tried
+	slog.InfoContext(ctx, "Tried candidate classes", "tried", len(attempts), "candidates", len(candidates), "outcome", outcome)
+	for _, attempt := range attempts {
+		slog.InfoContext(ctx, "Attempt", "result", attempt)
+	}
+
+	// Copy the reservations into the shared calendar
+	if b.cfg.CalDAV != nil {
+		if err := syncCalDAV(ctx, b.cfg.CalDAV, corePowerClient); err != nil {
+			slog.ErrorContext(ctx, "Error syncing calendar", "error", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "Reservation process completed")
+	return run, runErr
+}
+
+// noBookingError is the error for a run where every class tried was full or
+// not bookable. It wraps ErrClassFull whenever any class was full, not only
+// the last one, so the exit code reports full classes.
+func noBookingError(tried, full int, lastErr error) error {
+	err := fmt.Errorf("could not book any of %d classes tried, last error: %w", tried, lastErr)
+	if full > 0 && !errors.Is(lastErr, corepower.ErrClassFull) {
+		err = fmt.Errorf("%w (%d of them full: %w)", err, full, corepower.ErrClassFull)
+	}
+	return err
+}
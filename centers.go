@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/centers"
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/geo"
+	"github.com/eshaanm25/corepower/internal/opensearch"
+	"github.com/eshaanm25/corepower/internal/output"
+)
+
+// centerCacheMaxAge is how long the studio directory is reused before it is
+// fetched again
+var centerCacheMaxAge = 7 * 24 * time.Hour
+
+// runCenters lists the studios known to the search engine
+func runCenters(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("centers", flag.ExitOnError)
+	refresh := flags.Bool("refresh", false, "Ignore the cached studio list and fetch it again")
+	name := flags.String("name", "", "Only list studios whose name contains this")
+	latitude := flags.Float64("lat", 0, "Latitude to search around, used with -miles")
+	longitude := flags.Float64("long", 0, "Longitude to search around, used with -miles")
+	miles := flags.Float64("miles", 0, "Only list studios within this many miles of -lat/-long")
+	outputFlag := flags.String("output", "table", "Output format: table, json, yaml or csv")
+	flags.Parse(args)
+
+	format, err := output.Parse(*outputFlag)
+	if err != nil {
+		exit(exitUsage, "Invalid -output", "error", err)
+	}
+
+	searchClient := &opensearch.Client{}
+	searchClient.Initialize()
+
+	maxAge := centerCacheMaxAge
+	if *refresh {
+		maxAge = 0
+	}
+	directory, err := loadDirectory(ctx, searchClient, maxAge)
+	if err != nil {
+		fatal("Error loading studios", "error", err)
+	}
+
+	point := geo.Point{Latitude: *latitude, Longitude: *longitude}
+	list := directory.Centers
+	if *name != "" {
+		list = directory.Find(*name)
+	}
+	if *miles > 0 {
+		var nearby []centers.Center
+		for _, center := range directory.Near(point, *miles) {
+			if *name == "" || containsCenter(list, center.ID) {
+				nearby = append(nearby, center)
+			}
+		}
+		list = nearby
+	}
+
+	rows := make([]centerRow, 0, len(list))
+	for _, center := range list {
+		row := centerRow{Center: center}
+		if *miles > 0 {
+			distance := geo.DistanceMiles(point, center.Location)
+			row.DistanceMiles = &distance
+		}
+		rows = append(rows, row)
+	}
+	if err := output.Write(os.Stdout, format, rows, centerRows(rows)); err != nil {
+		fatal("Error writing output", "error", err)
+	}
+}
+
+// resolveCenters turns configured studio references into center IDs, only
+// loading the studio directory when a reference needs it. References that
+// match no studio are config errors, failing to load the directory is not.
+func resolveCenters(ctx context.Context, searchClient *opensearch.Client, refs []centers.Ref) ([]string, error) {
+	if !centers.NeedsDirectory(refs) {
+		var ids []string
+		for _, ref := range refs {
+			ids = append(ids, ref.ID)
+		}
+		return ids, nil
+	}
+
+	directory, err := loadDirectory(ctx, searchClient, centerCacheMaxAge)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := directory.Resolve(refs)
+	if err != nil {
+		return nil, &configError{err}
+	}
+	return ids, nil
+}
+
+func loadDirectory(ctx context.Context, searchClient *opensearch.Client, maxAge time.Duration) (*centers.Directory, error) {
+	path, err := centers.DefaultCachePath()
+	if err != nil {
+		return nil, err
+	}
+	return centers.LoadOrDiscover(ctx, searchClient, path, maxAge, clock.System.Now())
+}
+
+func containsCenter(list []centers.Center, id string) bool {
+	for _, center := range list {
+		if center.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/centers"
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/opensearch"
+	"github.com/eshaanm25/corepower/internal/retry"
+)
+
+func TestResolveCentersFailureCode(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	endpoint, _ := url.Parse(server.URL)
+	client := &opensearch.Client{SearchClient: server.Client(), Endpoint: endpoint, Timeout: 5 * time.Second, Retry: retry.Policy{MaxAttempts: 1}}
+	ctx := context.Background()
+	refs := []centers.Ref{{Name: "Triangle"}}
+
+	// The directory cannot be fetched
+	_, err := resolveCenters(ctx, client, refs)
+	if code := failureCode(err); code != exitNetwork {
+		t.Errorf("search engine down: got exit code %d (%v), want %d", code, err, exitNetwork)
+	}
+
+	// The directory is cached but has no such studio
+	path, err := centers.DefaultCachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(filepath.Dir(path)) != cacheDir {
+		t.Fatalf("cache path %s is outside the test's cache directory", path)
+	}
+	directory := &centers.Directory{UpdatedAt: clock.System.Now(), Centers: []centers.Center{{ID: "dom", Name: "Domain - Austin, TX"}}}
+	if err := directory.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	_, err = resolveCenters(ctx, client, refs)
+	if code := failureCode(err); code != exitConfig {
+		t.Errorf("unknown studio: got exit code %d (%v), want %d", code, err, exitConfig)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/eshaanm25/corepower/internal/cognito"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/history"
+	"github.com/eshaanm25/corepower/internal/retry"
+)
+
+// Exit codes, documented in the README. Codes below 10 are failures worth
+// alerting on, codes from 10 up are runs that ended without a new booking for
+// an expected reason.
+const (
+	exitOK              = 0  // Booked or waitlisted, or the command succeeded
+	exitError           = 1  // Any failure not covered below
+	exitUsage           = 2  // Unknown command or invalid flags
+	exitConfig          = 3  // Invalid config, unknown studio or unreadable calendar
+	exitAuth            = 4  // Wrong credentials or a rejected token
+	exitNetwork         = 5  // Services unreachable or unavailable after retries
+	exitNoMatch         = 10 // No class matched the preferences
+	exitAlreadyReserved = 11 // The best class was already reserved
+	exitClassFull       = 12 // Nothing booked and at least one class tried was full
+)
+
+// configError marks errors caused by the configuration rather than by the
+// services, such as a studio name that matches nothing
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string {
+	return e.err.Error()
+}
+
+func (e *configError) Unwrap() error {
+	return e.err
+}
+
+// fatal logs an error and exits with the code for the "error" attribute, if
+// any
+func fatal(msg string, args ...any) {
+	var err error
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == "error" {
+			err, _ = args[i+1].(error)
+		}
+	}
+	exit(failureCode(err), msg, args...)
+}
+
+// exit logs an error and exits with the given code
+func exit(code int, msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(code)
+}
+
+// failureCode returns the exit code for a failed command
+func failureCode(err error) int {
+	var retryErr *retry.Error
+	var netErr net.Error
+	var cfgErr *configError
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return exitError
+	case errors.Is(err, cognito.ErrNotAuthorized), errors.Is(err, corepower.ErrUnauthorized):
+		return exitAuth
+	case errors.Is(err, corepower.ErrClassFull):
+		return exitClassFull
+	// Checked before config errors, finding studios by name can fail to
+	// reach the search engine
+	case errors.As(err, &retryErr), errors.As(err, &netErr):
+		return exitNetwork
+	case errors.As(err, &cfgErr):
+		return exitConfig
+	}
+	return exitError
+}
+
+// outcomeCode returns the exit code for a booking run that did not fail
+func outcomeCode(outcome history.Outcome) int {
+	switch outcome {
+	case history.NoMatch:
+		return exitNoMatch
+	case history.AlreadyReserved:
+		return exitAlreadyReserved
+	}
+	return exitOK
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/eshaanm25/corepower/internal/cognito"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/retry"
+)
+
+func TestFailureCode(t *testing.T) {
+	notBookable := &corepower.APIError{StatusCode: 400, Message: "Your membership does not cover this class"}
+	full := &corepower.APIError{StatusCode: 400, Message: "This class is full"}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, exitError},
+		{"cancelled", fmt.Errorf("error searching: %w", context.Canceled), exitError},
+		{"bad credentials", fmt.Errorf("error authenticating: %w", cognito.ErrNotAuthorized), exitAuth},
+		{"rejected token", &corepower.APIError{StatusCode: 401}, exitAuth},
+		{"network", fmt.Errorf("error searching: %w", &net.OpError{Op: "dial", Err: errors.New("refused")}), exitNetwork},
+		{"retries exhausted", &retry.Error{Err: errors.New("503")}, exitNetwork},
+		{"config", &configError{errors.New("unknown studio")}, exitConfig},
+		{"other", errors.New("boom"), exitError},
+		{"every class full", noBookingError(2, 2, full), exitClassFull},
+		{"last class full after a not bookable one", noBookingError(2, 1, full), exitClassFull},
+		{"last class not bookable after a full one", noBookingError(2, 1, notBookable), exitClassFull},
+		{"no class full", noBookingError(2, 0, notBookable), exitError},
+	}
+	for _, test := range tests {
+		if got := failureCode(test.err); got != test.want {
+			t.Errorf("%s: failureCode(%v) = %d, want %d", test.name, test.err, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/caldav"
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/cognito"
+	"github.com/eshaanm25/corepower/internal/config"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/ical"
+)
+
+const icsProdID = "-//eshaanm25//corepower//EN"
+
+// runExport exports reservations to calendars
+func runExport(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: corepower export ics|caldav [flags]")
+		os.Exit(exitUsage)
+	}
+
+	switch args[0] {
+	case "ics":
+		runExportICS(ctx, args[1:])
+	case "caldav":
+		runExportCalDAV(ctx, args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, "Usage: corepower export ics|caldav [flags]")
+		os.Exit(exitUsage)
+	}
+}
+
+// runExportICS writes reservations to an ICS file or serves them as a feed
+func runExportICS(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("export ics", flag.ExitOnError)
+	username := flags.String("username", "", "CorePower username")
+	password := flags.String("password", "", "CorePower password")
+	output := flags.String("o", "", "File to write the calendar to, defaults to stdout")
+	listen := flags.String("listen", "", "Serve the calendar over HTTP on this address instead, e.g. :8080. A bare port binds to 127.0.0.1")
+	token := flags.String("token", os.Getenv("COREPOWER_FEED_TOKEN"), "Secret path segment the feed URL must contain, generated when empty (env COREPOWER_FEED_TOKEN)")
+	metricsAddr := flags.String("metrics", "", "With -listen, serve Prometheus metrics on this address, e.g. :9090")
+	flags.Parse(args)
+
+	if *username == "" || *password == "" {
+		exit(exitUsage, "Both -username and -password flags are required")
+	}
+
+	ctm := cognito.NewCognitoTokenManager(*username, *password)
+
+	if *listen != "" {
+		if *token == "" {
+			*token = newFeedToken()
+			// Printed rather than logged, so the secret stays out of log files
+			fmt.Fprintf(os.Stderr, "Generated feed token %s, pass it with -token to keep the feed URL stable\n", *token)
+		}
+		if *metricsAddr != "" {
+			go serveMetrics(ctx, *metricsAddr)
+		}
+		serveICS(ctx, loopbackDefault(*listen), *token, ctm)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := writeReservationsICS(ctx, &buf, ctm); err != nil {
+		fatal("Error exporting reservations", "error", err)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+		fatal("Error writing calendar", "error", err)
+	}
+	slog.Info("Wrote reservations", "path", *output)
+}
+
+// serveICS serves the reservations feed at /{token}/reservations.ics until ctx
+// is done. Calendar apps cannot send credentials with a subscription, so the
+// secret lives in the URL.
+func serveICS(ctx context.Context, addr, token string, ctm *cognito.CognitoTokenManager) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{token}/reservations.ics", func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.PathValue("token")), []byte(token)) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := writeReservationsICS(r.Context(), &buf, ctm); err != nil {
+			slog.Error("Error exporting reservations", "error", err)
+			http.Error(w, "could not load reservations", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Serving reservations calendar", "url", "http://"+addr+"/{token}/reservations.ics")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("Error serving calendar", "error", err)
+	}
+}
+
+// newFeedToken returns a random secret for the feed URL
+func newFeedToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// loopbackDefault binds an address without a host, such as :8080, to the
+// loopback interface only. Listening on every interface needs an explicit
+// host such as 0.0.0.0:8080.
+func loopbackDefault(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// writeReservationsICS fetches the current reservations and renders them as
+// an iCalendar feed
+func writeReservationsICS(ctx context.Context, w io.Writer, ctm *cognito.CognitoTokenManager) error {
+	corePowerClient, err := newCorePowerClient(ctx, ctm)
+	if err != nil {
+		return err
+	}
+
+	reservations, err := corePowerClient.Reservations(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ical.Write(w, icsProdID, "CorePower", reservationEvents(reservations), clock.System.Now())
+}
+
+// runExportCalDAV copies reservations into the CalDAV calendar from the config
+func runExportCalDAV(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("export caldav", flag.ExitOnError)
+	username := flags.String("username", "", "CorePower username")
+	password := flags.String("password", "", "CorePower password")
+	configPath := flags.String("config", "", "Path to a JSON config file with a caldav section")
+	flags.Parse(args)
+
+	if *username == "" || *password == "" {
+		exit(exitUsage, "Both -username and -password flags are required")
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exit(exitConfig, "Error loading config", "error", err)
+	}
+	if cfg.CalDAV == nil {
+		exit(exitConfig, "The config has no caldav section")
+	}
+
+	corePowerClient, err := newCorePowerClient(ctx, cognito.NewCognitoTokenManager(*username, *password))
+	if err != nil {
+		fatal("Error syncing calendar", "error", err)
+	}
+	if err := syncCalDAV(ctx, cfg.CalDAV, corePowerClient); err != nil {
+		fatal("Error syncing calendar", "error", err)
+	}
+}
+
+// syncCalDAV mirrors the current reservations into a CalDAV calendar, keyed
+// by reservation ID: new bookings are created, changed ones such as
+// instructor substitutions updated and cancelled ones deleted
+func syncCalDAV(ctx context.Context, cfg *config.CalDAV, corePowerClient *corepower.Client) error {
+	calendar, err := caldav.New(cfg.URL, cfg.Username, cfg.Password)
+	if err != nil {
+		return err
+	}
+
+	reservations, err := corePowerClient.Reservations(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := calendar.Sync(ctx, "reservation-", reservationEvents(reservations), clock.System.Now())
+	if err != nil {
+		return err
+	}
+	slog.Info("Synced calendar", "stored", result.Stored, "unchanged", result.Unchanged, "removed", result.Deleted)
+	return nil
+}
+
+// reservationEvents converts reservations to calendar events, skipping any
+// with unreadable times
+func reservationEvents(reservations []corepower.ReservationResponse) []ical.Event {
+	var events []ical.Event
+	for _, reservation := range reservations {
+		event, err := reservation.Event()
+		if err != nil {
+			slog.Error("Skipping reservation", "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// newCorePowerClient returns a reservations client with a current token
+func newCorePowerClient(ctx context.Context, ctm *cognito.CognitoTokenManager) (*corepower.Client, error) {
+	token, err := ctm.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating with CorePower API: %w", err)
+	}
+
+	corePowerClient := &corepower.Client{
+		Token: token,
+	}
+	corePowerClient.Initialize()
+	return corePowerClient, nil
+}
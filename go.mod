@@ -6,6 +6,7 @@ require (
 	github.com/aws/aws-sdk-go-v2 v1.36.3
 	github.com/aws/aws-sdk-go-v2/config v1.29.9
 	github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider v1.51.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
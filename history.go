@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/history"
+	"github.com/eshaanm25/corepower/internal/output"
+)
+
+// defaultHistoryPath is the history file used when -history is not given
+func defaultHistoryPath() string {
+	path, err := history.DefaultPath()
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// openHistory returns the store runs are recorded in, nil when path is empty.
+// A process opens it once so that everything it records goes through the
+// same store.
+func openHistory(path string) *history.Store {
+	if path == "" {
+		return nil
+	}
+	return history.Open(path)
+}
+
+// runHistory lists recorded booking runs
+func runHistory(args []string) {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	historyPath := flags.String("history", defaultHistoryPath(), "File runs are recorded in")
+	since := flags.String("since", "", "Only runs after this date (2006-01-02) or this long ago (e.g. 720h)")
+	until := flags.String("until", "", "Only runs before this date (2006-01-02)")
+	user := flags.String("user", "", "Only runs for this username")
+	outcome := flags.String("outcome", "", "Only runs with these comma separated outcomes: booked, waitlisted, already_reserved, no_match, failed")
+	center := flags.String("center", "", "Only runs whose class is at a studio containing this")
+	class := flags.String("class", "", "Only runs whose class name contains this")
+	weekday := flags.String("weekday", "", "Only runs whose class is on these comma separated weekdays, e.g. tue,thu")
+	outputFlag := flags.String("output", "table", "Output format: table, json, yaml or csv")
+	flags.Parse(args)
+
+	if *historyPath == "" {
+		exit(exitUsage, "The -history flag is required")
+	}
+	format, err := output.Parse(*outputFlag)
+	if err != nil {
+		exit(exitUsage, "Invalid -output", "error", err)
+	}
+
+	filter, err := historyFilter(*since, *until, *outcome, *weekday, clock.System.Now())
+	if err != nil {
+		exit(exitUsage, "Invalid filter", "error", err)
+	}
+	filter.User = *user
+	filter.Center = *center
+	filter.Class = *class
+
+	runs, err := openHistory(*historyPath).Runs(filter)
+	if err != nil {
+		fatal("Error reading history", "error", err)
+	}
+
+	if format == output.Table {
+		printRuns(runs)
+		return
+	}
+	if runs == nil {
+		runs = []history.Run{}
+	}
+	if err := output.Write(os.Stdout, format, runs, runRows(runs)); err != nil {
+		fatal("Error writing output", "error", err)
+	}
+}
+
+// historyFilter parses the filters of the history command that need parsing,
+// as given by flags or API query parameters
+func historyFilter(since, until, outcome, weekday string, now time.Time) (history.Filter, error) {
+	var filter history.Filter
+	var err error
+	if filter.Since, err = parseSince(since, now); err != nil {
+		return filter, fmt.Errorf("invalid since: %v", err)
+	}
+	if until != "" {
+		if filter.Until, err = time.ParseInLocation("2006-01-02", until, time.Local); err != nil {
+			return filter, fmt.Errorf("invalid until: %v", err)
+		}
+	}
+	for _, value := range splitList(outcome) {
+		filter.Outcomes = append(filter.Outcomes, history.Outcome(value))
+	}
+	for _, value := range splitList(weekday) {
+		day, ok := corepower.ParseWeekday(value)
+		if !ok {
+			return filter, fmt.Errorf("invalid weekday %q", value)
+		}
+		filter.Weekdays = append(filter.Weekdays, day)
+	}
+	return filter, nil
+}
+
+// printRuns writes runs as a table followed by a count per outcome
+func printRuns(runs []history.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tOUTCOME\tCLASS\tCENTER\tSTART\tERROR")
+	counts := map[history.Outcome]int{}
+	for _, run := range runs {
+		counts[run.Outcome]++
+		class, center, start := "-", "-", "-"
+		if run.Class != nil {
+			class = run.Class.ClassCategoryName
+			center = run.Class.CenterName
+			start = run.Class.StartTime.Format("Mon Jan 2 3:04 PM")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", run.Time.Local().Format("2006-01-02 15:04"), run.Outcome, class, center, start, run.Error)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d runs", len(runs))
+	for _, outcome := range []history.Outcome{history.Booked, history.Waitlisted, history.AlreadyReserved, history.NoMatch, history.Failed} {
+		if counts[outcome] > 0 {
+			fmt.Printf(", %d %s", counts[outcome], outcome)
+		}
+	}
+	fmt.Println()
+}
+
+// parseSince reads a date or a duration before now
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+func splitList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
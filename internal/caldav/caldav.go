@@ -0,0 +1,290 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/ical"
+)
+
+var (
+	defaultTimeout = 30 * time.Second
+	prodID         = "-//eshaanm25//corepower//EN"
+)
+
+// Client writes events into a single CalDAV calendar collection
+type Client struct {
+	CalendarURL *url.URL // Collection URL, e.g. https://dav.example.com/calendars/me/yoga/
+	Username    string
+	Password    string
+	HTTPClient  *http.Client
+}
+
+// New returns a client for the calendar collection at calendarURL
+func New(calendarURL, username, password string) (*Client, error) {
+	parsed, err := url.Parse(calendarURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar URL: %v", err)
+	}
+	if !strings.HasSuffix(parsed.Path, "/") {
+		parsed.Path += "/"
+	}
+
+	return &Client{
+		CalendarURL: parsed,
+		Username:    username,
+		Password:    password,
+		HTTPClient:  &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// ResourceName returns the file name an event is stored under, derived from
+// its UID so that the same event always maps to the same resource
+func ResourceName(uid string) string {
+	name, _, _ := strings.Cut(uid, "@")
+	return strings.ReplaceAll(name, "/", "-") + ".ics"
+}
+
+// Put creates or replaces an event. A non-empty etag is sent as If-Match, so
+// that an event changed on the server since it was listed is not overwritten.
+// Without an etag the event is only created, never overwriting one stored
+// since the listing.
+func (c *Client) Put(ctx context.Context, event ical.Event, stamp time.Time, etag string) error {
+	var body bytes.Buffer
+	if err := ical.WriteEvent(&body, prodID, event, stamp); err != nil {
+		return fmt.Errorf("error rendering event: %v", err)
+	}
+
+	headers := map[string]string{"Content-Type": "text/calendar; charset=utf-8"}
+	if etag != "" {
+		headers["If-Match"] = etag
+	} else {
+		headers["If-None-Match"] = "*"
+	}
+	resp, err := c.do(ctx, "PUT", ResourceName(event.UID), &body, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		if etag == "" {
+			return fmt.Errorf("error storing event %s: created on the server since it was listed", event.UID)
+		}
+		return fmt.Errorf("error storing event %s: changed on the server since it was listed", event.UID)
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error storing event %s: received status code %d", event.UID, resp.StatusCode)
+	}
+	return nil
+}
+
+// Delete removes the resource with the given name, reporting whether it
+// existed. Missing resources are not an error.
+func (c *Client) Delete(ctx context.Context, name string) (bool, error) {
+	resp, err := c.do(ctx, "DELETE", name, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return false, nil
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("error deleting %s: received status code %d", name, resp.StatusCode)
+	}
+	return true, nil
+}
+
+// Resource is an event stored in the collection
+type Resource struct {
+	Name  string
+	ETag  string
+	Event *ical.Event // Nil when the stored calendar data could not be read
+}
+
+// List returns the event resources in the collection that end after from,
+// with their ETags and stored events
+func (c *Client) List(ctx context.Context, from time.Time) ([]Resource, error) {
+	body := strings.NewReader(`<?xml version="1.0" encoding="utf-8"?>` +
+		`<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` +
+		`<d:prop><d:getetag/><c:calendar-data/></d:prop>` +
+		`<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT">` +
+		`<c:time-range start="` + from.UTC().Format("20060102T150405Z") + `"/>` +
+		`</c:comp-filter></c:comp-filter></c:filter>` +
+		`</c:calendar-query>`)
+	resp, err := c.do(ctx, "REPORT", "", body, map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("error listing calendar: received status code %d", resp.StatusCode)
+	}
+
+	var multistatus struct {
+		Responses []struct {
+			Href      string `xml:"href"`
+			Propstats []struct {
+				ETag string `xml:"prop>getetag"`
+				Data string `xml:"prop>calendar-data"`
+			} `xml:"propstat"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&multistatus); err != nil {
+		return nil, fmt.Errorf("error decoding calendar listing: %v", err)
+	}
+
+	var resources []Resource
+	for _, response := range multistatus.Responses {
+		href, err := url.PathUnescape(response.Href)
+		if err != nil {
+			href = response.Href
+		}
+		// The collection lists itself too
+		if strings.HasSuffix(href, "/") {
+			continue
+		}
+
+		resource := Resource{Name: path.Base(href)}
+		for _, propstat := range response.Propstats {
+			if propstat.ETag != "" {
+				resource.ETag = propstat.ETag
+			}
+			if propstat.Data == "" {
+				continue
+			}
+			calendar, err := ical.Parse(strings.NewReader(propstat.Data), time.UTC)
+			if err != nil || len(calendar.Events) == 0 {
+				slog.WarnContext(ctx, "Could not read stored event", "resource", resource.Name, "error", err)
+				continue
+			}
+			resource.Event = &calendar.Events[0]
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// SyncResult counts what Sync changed
+type SyncResult struct {
+	Stored    int
+	Unchanged int
+	Deleted   int
+}
+
+// Sync makes the resources whose names start with prefix match events, which
+// are taken to be every event starting from stamp on. Changed and new events
+// are stored, and cancelled events deleted. Resources with no matching event
+// are deleted only if they start after stamp, so that past events the listing
+// no longer includes are kept. Other resources in the calendar are left
+// alone.
+func (c *Client) Sync(ctx context.Context, prefix string, events []ical.Event, stamp time.Time) (SyncResult, error) {
+	var result SyncResult
+
+	listed, err := c.List(ctx, stamp)
+	if err != nil {
+		return result, err
+	}
+	existing := map[string]Resource{}
+	for _, resource := range listed {
+		existing[resource.Name] = resource
+	}
+
+	current := map[string]bool{}
+	for _, event := range events {
+		name := ResourceName(event.UID)
+		current[name] = true
+		stored, found := existing[name]
+
+		if event.Status == "CANCELLED" {
+			deleted, err := c.Delete(ctx, name)
+			if err != nil {
+				return result, err
+			}
+			if deleted {
+				result.Deleted++
+			}
+			continue
+		}
+
+		if found && stored.Event != nil && sameEvent(*stored.Event, event) {
+			result.Unchanged++
+			continue
+		}
+		if err := c.Put(ctx, event, stamp, stored.ETag); err != nil {
+			return result, err
+		}
+		result.Stored++
+	}
+
+	for _, resource := range listed {
+		if !strings.HasPrefix(resource.Name, prefix) || current[resource.Name] {
+			continue
+		}
+		// Without its start the resource may be a class already taken
+		if resource.Event == nil || resource.Event.Start.Before(stamp) {
+			continue
+		}
+		deleted, err := c.Delete(ctx, resource.Name)
+		if err != nil {
+			return result, err
+		}
+		if deleted {
+			result.Deleted++
+		}
+	}
+
+	return result, nil
+}
+
+// sameEvent reports whether storing event would leave stored unchanged, apart
+// from its DTSTAMP
+func sameEvent(stored, event ical.Event) bool {
+	return stored.UID == event.UID &&
+		stored.Summary == event.Summary &&
+		stored.Location == event.Location &&
+		stored.Description == event.Description &&
+		stored.Status == event.Status &&
+		stored.Start.Equal(event.Start) &&
+		stored.End.Equal(event.End)
+}
+
+func (c *Client) do(ctx context.Context, method, name string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	target := c.CalendarURL
+	if name != "" {
+		target = c.CalendarURL.JoinPath(name)
+	}
+
+	slog.DebugContext(ctx, "CalDAV request", "method", method, "resource", name)
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %v", err)
+	}
+	return resp, nil
+}
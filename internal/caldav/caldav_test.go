@@ -0,0 +1,277 @@
+package caldav
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/ical"
+)
+
+// fakeServer is a CalDAV collection held in memory. It answers REPORT with
+// every resource, ignoring the time range, so that Sync's own handling of
+// past events is exercised.
+type fakeServer struct {
+	mu        sync.Mutex
+	resources map[string]string // Name to calendar data
+	etags     map[string]int
+	requests  []string // "METHOD name"
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, *Client) {
+	t.Helper()
+	fake := &fakeServer{resources: map[string]string{}, etags: map[string]int{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := New(server.URL+"/cal", "user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, client
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, password, _ := r.BasicAuth(); user != "user" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/cal/")
+	f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+name))
+
+	switch r.Method {
+	case "REPORT":
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Depth") != "1" || !strings.Contains(string(body), "time-range") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var listing strings.Builder
+		listing.WriteString(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+		names := make([]string, 0, len(f.resources))
+		for name := range f.resources {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Fprintf(&listing, `<d:response><d:href>/cal/%s</d:href><d:propstat><d:prop>`+
+				`<d:getetag>"%d"</d:getetag><c:calendar-data>%s</c:calendar-data>`+
+				`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+				name, f.etags[name], f.resources[name])
+		}
+		listing.WriteString(`</d:multistatus>`)
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, listing.String())
+
+	case "PUT":
+		_, exists := f.resources[name]
+		if match := r.Header.Get("If-Match"); match != "" && (!exists || match != fmt.Sprintf(`"%d"`, f.etags[name])) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.resources[name] = string(body)
+		f.etags[name]++
+		if exists {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+
+	case "DELETE":
+		if _, exists := f.resources[name]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.resources, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// store puts an event straight into the collection
+func (f *fakeServer) store(t *testing.T, event ical.Event) {
+	t.Helper()
+	var body strings.Builder
+	if err := ical.WriteEvent(&body, prodID, event, event.Start); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resources[ResourceName(event.UID)] = body.String()
+	f.etags[ResourceName(event.UID)] = 1
+}
+
+func (f *fakeServer) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for name := range f.resources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (f *fakeServer) takeRequests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+func reservation(id int, start time.Time) ical.Event {
+	return ical.Event{
+		UID:     fmt.Sprintf("reservation-%d@corepoweryoga.com", id),
+		Summary: "Yoga Sculpt",
+		Start:   start,
+		End:     start.Add(time.Hour),
+	}
+}
+
+func TestSync(t *testing.T) {
+	now := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	fake, client := newFakeServer(t)
+	ctx := t.Context()
+
+	attended := reservation(1, now.Add(-48*time.Hour))
+	unchanged := reservation(2, now.Add(24*time.Hour))
+	substituted := reservation(3, now.Add(48*time.Hour))
+	dropped := reservation(4, now.Add(72*time.Hour))
+	cancelled := reservation(5, now.Add(96*time.Hour))
+	for _, event := range []ical.Event{attended, unchanged, substituted, dropped, cancelled} {
+		fake.store(t, event)
+	}
+	fake.store(t, ical.Event{UID: "dentist", Start: now.Add(24 * time.Hour), End: now.Add(25 * time.Hour)})
+
+	substituted.Description = "Instructor substituted"
+	cancelled.Status = "CANCELLED"
+	added := reservation(6, now.Add(120*time.Hour))
+	goneAlready := reservation(7, now.Add(144*time.Hour))
+	goneAlready.Status = "CANCELLED"
+
+	result, err := client.Sync(ctx, "reservation-", []ical.Event{unchanged, substituted, cancelled, added, goneAlready}, now)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	want := SyncResult{Stored: 2, Unchanged: 1, Deleted: 2}
+	if result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+	wantNames := []string{"dentist.ics", "reservation-1.ics", "reservation-2.ics", "reservation-3.ics", "reservation-6.ics"}
+	if got := fake.names(); !slices.Equal(got, wantNames) {
+		t.Errorf("resources = %v, want %v", got, wantNames)
+	}
+	wantRequests := []string{
+		"REPORT",
+		"PUT reservation-3.ics",
+		"DELETE reservation-5.ics",
+		"PUT reservation-6.ics",
+		"DELETE reservation-7.ics",
+		"DELETE reservation-4.ics",
+	}
+	if got := fake.takeRequests(); !slices.Equal(got, wantRequests) {
+		t.Errorf("requests = %v, want %v", got, wantRequests)
+	}
+
+	// A second run with nothing changed leaves the calendar alone
+	result, err = client.Sync(ctx, "reservation-", []ical.Event{unchanged, substituted, cancelled, added}, now)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if want := (SyncResult{Unchanged: 3}); result != want {
+		t.Errorf("second result = %+v, want %+v", result, want)
+	}
+	for _, request := range fake.takeRequests() {
+		if strings.HasPrefix(request, "PUT") {
+			t.Errorf("unexpected %s", request)
+		}
+	}
+}
+
+func TestPutIfMatch(t *testing.T) {
+	fake, client := newFakeServer(t)
+	ctx := t.Context()
+	event := reservation(1, time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC))
+	fake.store(t, event)
+
+	if err := client.Put(ctx, event, event.Start, `"1"`); err != nil {
+		t.Errorf("Put with current ETag: %v", err)
+	}
+	if err := client.Put(ctx, event, event.Start, `"1"`); err == nil || !strings.Contains(err.Error(), "changed on the server") {
+		t.Errorf("Put with stale ETag = %v, want a conflict", err)
+	}
+}
+
+func TestPutIfNoneMatch(t *testing.T) {
+	fake, client := newFakeServer(t)
+	ctx := t.Context()
+	event := reservation(1, time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC))
+
+	if err := client.Put(ctx, event, event.Start, ""); err != nil {
+		t.Fatalf("Put of a new event: %v", err)
+	}
+	if err := client.Put(ctx, event, event.Start, ""); err == nil || !strings.Contains(err.Error(), "created on the server") {
+		t.Errorf("Put without ETag over a stored event = %v, want a conflict", err)
+	}
+	if len(fake.resources) != 1 {
+		t.Errorf("got %d resources, want 1", len(fake.resources))
+	}
+}
+
+func TestList(t *testing.T) {
+	fake, client := newFakeServer(t)
+	start := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	fake.store(t, reservation(1, start))
+	fake.mu.Lock()
+	fake.resources["broken.ics"] = "not a calendar"
+	fake.mu.Unlock()
+
+	resources, err := client.List(t.Context(), start)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("List returned %d resources, want 2", len(resources))
+	}
+	if resources[0].Name != "broken.ics" || resources[0].Event != nil {
+		t.Errorf("broken resource = %+v", resources[0])
+	}
+	stored := resources[1]
+	if stored.Name != "reservation-1.ics" || stored.ETag != `"1"` || stored.Event == nil || !stored.Event.Start.Equal(start) {
+		t.Errorf("stored resource = %+v", stored)
+	}
+}
+
+func TestDeleteMissing(t *testing.T) {
+	_, client := newFakeServer(t)
+	deleted, err := client.Delete(t.Context(), "reservation-9.ics")
+	if err != nil || deleted {
+		t.Errorf("Delete of a missing resource = %v, %v, want false, nil", deleted, err)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	_, client := newFakeServer(t)
+	client.Password = "wrong"
+	if _, err := client.List(t.Context(), time.Now()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("List with a wrong password = %v, want a 401 error", err)
+	}
+}
@@ -0,0 +1,247 @@
+package centers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/geo"
+	"github.com/eshaanm25/corepower/internal/opensearch"
+)
+
+// Center is a CorePower studio
+type Center struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Location geo.Point `json:"location"`
+}
+
+// ShortName returns the studio name without the city suffix, e.g. "Triangle"
+// for "Triangle - Austin, TX"
+func (c Center) ShortName() string {
+	return strings.Split(c.Name, ` - `)[0]
+}
+
+// Directory is the list of known studios
+type Directory struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Centers   []Center  `json:"centers"`
+}
+
+// Ref refers to one or more studios by ID, by name or by distance from a
+// point. Exactly one of the fields should be set.
+type Ref struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	Near *Near  `json:"near,omitempty"`
+}
+
+// Near selects every studio within Miles of a point
+type Near struct {
+	geo.Point
+	Miles float64 `json:"miles"`
+}
+
+// discoveryWindow is how far ahead Discover looks for scheduled classes. Only
+// studios with classes on the schedule are found.
+var discoveryWindow = 14 * 24 * time.Hour
+
+// Discover asks the search engine for every studio with classes scheduled in
+// the coming weeks
+func Discover(ctx context.Context, client *opensearch.Client, now time.Time) (*Directory, error) {
+	query := opensearch.NewQuery().
+		WhereAny(opensearch.StartingBetween(now, now.Add(discoveryWindow))).
+		GroupBy(opensearch.FieldCenterID).
+		SortBy(opensearch.FieldCenterName, "asc").
+		Fields(
+			opensearch.FieldCenterID,
+			opensearch.FieldCenterName,
+			opensearch.FieldCenterLat,
+			opensearch.FieldCenterLong,
+		)
+
+	directory := &Directory{UpdatedAt: now}
+	seen := map[string]bool{}
+	for class, err := range client.Classes(ctx, query) {
+		if err != nil {
+			return nil, fmt.Errorf("error discovering centers: %w", err)
+		}
+		if class.CenterID.Raw == "" || seen[class.CenterID.Raw] {
+			continue
+		}
+		seen[class.CenterID.Raw] = true
+
+		directory.Centers = append(directory.Centers, Center{
+			ID:   class.CenterID.Raw,
+			Name: class.CenterName.Raw,
+			Location: geo.Point{
+				Latitude:  class.CenterLocationLatitude.Raw,
+				Longitude: class.CenterLocationLongitude.Raw,
+			},
+		})
+	}
+
+	return directory, nil
+}
+
+// DefaultCachePath returns where the directory is cached between runs
+func DefaultCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding cache directory: %v", err)
+	}
+	return filepath.Join(dir, "corepower", "centers.json"), nil
+}
+
+// Load reads a cached directory
+func Load(path string) (*Directory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var directory Directory
+	if err := json.Unmarshal(data, &directory); err != nil {
+		return nil, fmt.Errorf("error decoding center cache %s: %v", path, err)
+	}
+	return &directory, nil
+}
+
+// Save writes the directory to path, creating parent directories as needed
+func (d *Directory) Save(path string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding center cache: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating cache directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing center cache: %v", err)
+	}
+	return nil
+}
+
+// LoadOrDiscover returns the cached directory at path, refreshing it from the
+// search engine when it is missing or older than maxAge
+func LoadOrDiscover(ctx context.Context, client *opensearch.Client, path string, maxAge time.Duration, now time.Time) (*Directory, error) {
+	directory, err := Load(path)
+	if err == nil && now.Sub(directory.UpdatedAt) < maxAge {
+		slog.DebugContext(ctx, "Using cached studio directory", "path", path, "studios", len(directory.Centers), "updated_at", directory.UpdatedAt)
+		return directory, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	directory, err = Discover(ctx, client, now)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Discovered studios", "studios", len(directory.Centers))
+	if err := directory.Save(path); err != nil {
+		return nil, err
+	}
+	return directory, nil
+}
+
+// Find returns the studios matching a name. An exact match on the full or
+// short name wins, otherwise every studio containing the name is returned.
+func (d *Directory) Find(name string) []Center {
+	var contains []Center
+	for _, center := range d.Centers {
+		if strings.EqualFold(center.Name, name) || strings.EqualFold(center.ShortName(), name) {
+			return []Center{center}
+		}
+		if strings.Contains(strings.ToLower(center.Name), strings.ToLower(name)) {
+			contains = append(contains, center)
+		}
+	}
+	return contains
+}
+
+// Near returns the studios within miles of a point, closest first
+func (d *Directory) Near(point geo.Point, miles float64) []Center {
+	var nearby []Center
+	for _, center := range d.Centers {
+		if geo.DistanceMiles(point, center.Location) <= miles {
+			nearby = append(nearby, center)
+		}
+	}
+	slices.SortStableFunc(nearby, func(a, b Center) int {
+		da, db := geo.DistanceMiles(point, a.Location), geo.DistanceMiles(point, b.Location)
+		switch {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+		return 0
+	})
+	return nearby
+}
+
+// Resolve turns refs into a de-duplicated list of center IDs. A point with no
+// studios in range is skipped, e.g. an anchor for a trip, unless no reference
+// matches any studio.
+func (d *Directory) Resolve(refs []Ref) ([]string, error) {
+	var ids []string
+	add := func(id string) {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	for _, ref := range refs {
+		switch {
+		case ref.ID != "":
+			add(ref.ID)
+		case ref.Name != "":
+			matches := d.Find(ref.Name)
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no studio named %q", ref.Name)
+			}
+			if len(matches) > 1 {
+				var names []string
+				for _, match := range matches {
+					names = append(names, match.Name)
+				}
+				return nil, fmt.Errorf("studio name %q is ambiguous: %s", ref.Name, strings.Join(names, ", "))
+			}
+			add(matches[0].ID)
+		case ref.Near != nil:
+			matches := d.Near(ref.Near.Point, ref.Near.Miles)
+			if len(matches) == 0 {
+				slog.Warn("No studios in range, skipping", "latitude", ref.Near.Latitude, "longitude", ref.Near.Longitude, "miles", ref.Near.Miles)
+			}
+			for _, match := range matches {
+				add(match.ID)
+			}
+		default:
+			return nil, errors.New("studio reference needs an id, name or near")
+		}
+	}
+
+	if len(ids) == 0 && len(refs) > 0 {
+		return nil, errors.New("no studios within range of any configured point")
+	}
+	return ids, nil
+}
+
+// NeedsDirectory reports whether resolving refs requires the studio directory,
+// i.e. whether any of them is not a plain ID
+func NeedsDirectory(refs []Ref) bool {
+	for _, ref := range refs {
+		if ref.ID == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package centers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/geo"
+	"github.com/eshaanm25/corepower/internal/opensearch"
+	"github.com/eshaanm25/corepower/internal/retry"
+)
+
+var (
+	downtown = geo.Point{Latitude: 30.2672, Longitude: -97.7431}
+
+	directory = &Directory{Centers: []Center{
+		{ID: "tri", Name: "Triangle - Austin, TX", Location: geo.Point{Latitude: 30.3183, Longitude: -97.7236}},
+		{ID: "dtx", Name: "Downtown - Austin, TX", Location: geo.Point{Latitude: 30.2660, Longitude: -97.7467}},
+		{ID: "dom", Name: "Domain - Austin, TX", Location: geo.Point{Latitude: 30.4021, Longitude: -97.7253}},
+		{ID: "ddl", Name: "Downtown - Dallas, TX", Location: geo.Point{Latitude: 32.7767, Longitude: -96.7970}},
+		{ID: "tri2", Name: "Triangle North - Austin, TX", Location: geo.Point{Latitude: 30.3300, Longitude: -97.7200}},
+	}}
+)
+
+func ids(list []Center) []string {
+	var ids []string
+	for _, center := range list {
+		ids = append(ids, center.ID)
+	}
+	return ids
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Triangle", []string{"tri"}},                     // Short name beats the substring match
+		{"triangle - austin, tx", []string{"tri"}},        // Full name, any case
+		{"Down", []string{"dtx", "ddl"}},                  // Ambiguous
+		{"dallas", []string{"ddl"}},                       // Substring
+		{"Austin", []string{"tri", "dtx", "dom", "tri2"}}, // Every studio in the city
+		{"Houston", nil},
+	}
+	for _, test := range tests {
+		if got := ids(directory.Find(test.name)); !slices.Equal(got, test.want) {
+			t.Errorf("Find(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNear(t *testing.T) {
+	tests := []struct {
+		miles float64
+		want  []string
+	}{
+		{0.1, nil},
+		{1, []string{"dtx"}},
+		{5, []string{"dtx", "tri", "tri2"}},
+		{15, []string{"dtx", "tri", "tri2", "dom"}},
+		{500, []string{"dtx", "tri", "tri2", "dom", "ddl"}},
+	}
+	for _, test := range tests {
+		if got := ids(directory.Near(downtown, test.miles)); !slices.Equal(got, test.want) {
+			t.Errorf("Near(%v miles) = %v, want %v", test.miles, got, test.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		refs    []Ref
+		want    []string
+		wantErr string
+	}{
+		{"ids", []Ref{{ID: "a"}, {ID: "b"}}, []string{"a", "b"}, ""},
+		{"name", []Ref{{Name: "Domain"}}, []string{"dom"}, ""},
+		{"near", []Ref{{Near: &Near{Point: downtown, Miles: 5}}}, []string{"dtx", "tri", "tri2"}, ""},
+		{"duplicates", []Ref{{ID: "tri"}, {Name: "Triangle"}, {Near: &Near{Point: downtown, Miles: 5}}}, []string{"tri", "dtx", "tri2"}, ""},
+		{"unknown name", []Ref{{Name: "Houston"}}, nil, `no studio named "Houston"`},
+		{"ambiguous name", []Ref{{Name: "Down"}}, nil, "is ambiguous: Downtown - Austin, TX, Downtown - Dallas, TX"},
+		{"nothing near one point", []Ref{{ID: "a"}, {Near: &Near{Point: geo.Point{Latitude: 0, Longitude: 0}, Miles: 5}}}, []string{"a"}, ""},
+		{"nothing near any point", []Ref{{Near: &Near{Point: geo.Point{Latitude: 0, Longitude: 0}, Miles: 5}}}, nil, "no studios within range"},
+		{"empty", []Ref{{}}, nil, "needs an id, name or near"},
+	}
+	for _, test := range tests {
+		got, err := directory.Resolve(test.refs)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNeedsDirectory(t *testing.T) {
+	if NeedsDirectory([]Ref{{ID: "a"}, {ID: "b"}}) {
+		t.Error("plain IDs need the directory")
+	}
+	if !NeedsDirectory([]Ref{{ID: "a"}, {Name: "Domain"}}) {
+		t.Error("a name does not need the directory")
+	}
+}
+
+func TestLoadOrDiscover(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, `{"meta":{"page":{"current":1,"total_pages":1}},"results":[
+			{"center.id":{"raw":"tri"},"center.name":{"raw":"Triangle - Austin, TX"},"center.location.latitude":{"raw":30.3183},"center.location.longitude":{"raw":-97.7236}},
+			{"center.id":{"raw":"tri"},"center.name":{"raw":"Triangle - Austin, TX"}},
+			{"center.id":{"raw":"dom"},"center.name":{"raw":"Domain - Austin, TX"},"center.location.latitude":{"raw":30.4021},"center.location.longitude":{"raw":-97.7253}}
+		]}`)
+	}))
+	defer server.Close()
+	endpoint, _ := url.Parse(server.URL)
+	client := &opensearch.Client{SearchClient: server.Client(), Endpoint: endpoint, Timeout: 5 * time.Second, Retry: retry.Policy{MaxAttempts: 1}}
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "centers.json")
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Missing cache, discovered and saved
+	got, err := LoadOrDiscover(ctx, client, path, time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids(got.Centers), []string{"tri", "dom"}) || got.Centers[0].Location.Latitude != 30.3183 {
+		t.Errorf("got %+v", got.Centers)
+	}
+
+	// Fresh cache, no request
+	if _, err := LoadOrDiscover(ctx, client, path, time.Hour, now.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("got %d requests with a fresh cache, want 1", n)
+	}
+
+	// Stale cache, discovered again
+	got, err = LoadOrDiscover(ctx, client, path, time.Hour, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("got %d requests with a stale cache, want 2", n)
+	}
+	if !got.UpdatedAt.Equal(now.Add(2 * time.Hour)) {
+		t.Errorf("got UpdatedAt %v", got.UpdatedAt)
+	}
+}
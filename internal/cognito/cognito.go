@@ -2,22 +2,35 @@ package cognito
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+
+	"github.com/eshaanm25/corepower/internal/metrics"
 )
 
 var (
-	clientID = "5l74ttc4m9etagg1jh8n5b8vic"
-	region   = "us-west-1"
+	clientID       = "5l74ttc4m9etagg1jh8n5b8vic"
+	region         = "us-west-1"
+	defaultTimeout = 30 * time.Second
 )
 
+// ErrNotAuthorized is returned when Cognito rejects the username or password
+var ErrNotAuthorized = errors.New("incorrect username or password")
+
 type CognitoTokenManager struct {
-	username string
-	password string
-	idToken  string
+	mu        sync.Mutex
+	username  string
+	password  string
+	idToken   string
+	expiresAt time.Time
 }
 
 func NewCognitoTokenManager(username, password string) *CognitoTokenManager {
@@ -29,12 +42,16 @@ func NewCognitoTokenManager(username, password string) *CognitoTokenManager {
 
 // Authenticate and get a new token
 func (ctm *CognitoTokenManager) Authenticate(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
 	if err != nil {
 		return fmt.Errorf("unable to load AWS SDK config: %v", err)
 	}
 
 	svc := cognitoidentityprovider.NewFromConfig(cfg)
+	slog.DebugContext(ctx, "Authenticating", "username", ctm.username)
 
 	authParams := &cognitoidentityprovider.InitiateAuthInput{
 		AuthFlow: "USER_PASSWORD_AUTH",
@@ -47,14 +64,50 @@ func (ctm *CognitoTokenManager) Authenticate(ctx context.Context) error {
 
 	resp, err := svc.InitiateAuth(ctx, authParams)
 	if err != nil {
-		return fmt.Errorf("failed to authenticate user: %v", err)
+		metrics.AuthRefreshes.Inc("error")
+		var notAuthorized *types.NotAuthorizedException
+		var userNotFound *types.UserNotFoundException
+		if errors.As(err, &notAuthorized) || errors.As(err, &userNotFound) {
+			return fmt.Errorf("failed to authenticate user: %w", ErrNotAuthorized)
+		}
+		return fmt.Errorf("failed to authenticate user: %w", err)
 	}
+	metrics.AuthRefreshes.Inc("success")
 
+	expiresIn := time.Duration(resp.AuthenticationResult.ExpiresIn) * time.Second
+	slog.DebugContext(ctx, "Authenticated", "username", ctm.username, "expires_in", expiresIn)
+
+	ctm.mu.Lock()
+	defer ctm.mu.Unlock()
 	ctm.idToken = *resp.AuthenticationResult.IdToken
+	ctm.expiresAt = time.Now().Add(expiresIn)
 	return nil
 }
 
+// Token returns a valid ID token, authenticating again when the current one
+// is missing or about to expire. Long-running commands use it instead of
+// GetIdToken.
+func (ctm *CognitoTokenManager) Token(ctx context.Context) (string, error) {
+	ctm.mu.Lock()
+	fresh := ctm.idToken != "" && time.Until(ctm.expiresAt) > time.Minute
+	token := ctm.idToken
+	ctm.mu.Unlock()
+	if fresh {
+		return token, nil
+	}
+	if token != "" {
+		slog.InfoContext(ctx, "Token about to expire, authenticating again")
+	}
+
+	if err := ctm.Authenticate(ctx); err != nil {
+		return "", err
+	}
+	return ctm.GetIdToken(), nil
+}
+
 // GetIdToken returns the current ID token
 func (ctm *CognitoTokenManager) GetIdToken() string {
+	ctm.mu.Lock()
+	defer ctm.mu.Unlock()
 	return ctm.idToken
 }
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/eshaanm25/corepower/internal/centers"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/history"
+	"github.com/eshaanm25/corepower/internal/notify"
+)
+
+// Config is the user configuration, read from a JSON file
+type Config struct {
+	// Studios to search, by ID, name or distance from a point. Studios near
+	// the profile's anchors are searched as well.
+	Centers []centers.Ref `json:"centers"`
+
+	// Which classes to book and where from
+	Profile corepower.Profile `json:"profile"`
+
+	// Calendar that booked classes are copied into
+	CalDAV *CalDAV `json:"caldav,omitempty"`
+
+	// Where to send booking outcomes
+	Notify []notify.SinkConfig `json:"notify,omitempty"`
+
+	// Ranks classes by how likely they are to be attended, learned from
+	// collected snapshots. Off when missing.
+	Predict *history.PredictConfig `json:"predict,omitempty"`
+
+	// Accounts the HTTP API of the serve command acts for
+	Serve *Serve `json:"serve,omitempty"`
+}
+
+// CalDAV is a calendar collection on a CalDAV server
+type CalDAV struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"` // Falls back to $COREPOWER_CALDAV_PASSWORD
+}
+
+// Serve configures the HTTP API. Each request acts for the user whose API key
+// it carries.
+type Serve struct {
+	Users []User `json:"users"`
+}
+
+// User is a CorePower account reachable through the HTTP API
+type User struct {
+	APIKey   string `json:"api_key"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Default returns the configuration used when no file is given
+func Default() *Config {
+	return &Config{
+		Centers: []centers.Ref{
+			{ID: "96efaaaa-b040-4d12-8829-51317dd8c1c2"}, // Cedar Park
+			{ID: "d810c305-2816-4a92-8c3d-47fe0a69d63a"}, // Monarch
+			{ID: "a9d80dd6-0610-4584-b945-f297122d6268"}, // Mueller
+			{ID: "5262c2d4-7f8f-4d15-9adf-5ba107409b30"}, // Triangle
+		},
+		Profile: corepower.DefaultProfile(),
+	}
+}
+
+// Load reads the configuration at path. An empty path returns the defaults.
+// A file without a profile uses the default profile, and the default studios
+// unless it lists its own. Settings missing from a configured profile take
+// their default values one by one.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		cfg := Default()
+		if err := cfg.Profile.LoadLocation(); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %v", err)
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %v", path, err)
+	}
+	if err := applyDefaults(cfg, data); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %v", path, err)
+	}
+	if err := cfg.Profile.LoadLocation(); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %v", path, err)
+	}
+	if err := cfg.Profile.Horizon.Validate(); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %v", path, err)
+	}
+	if cfg.CalDAV != nil && cfg.CalDAV.Password == "" {
+		cfg.CalDAV.Password = os.Getenv("COREPOWER_CALDAV_PASSWORD")
+	}
+	if len(cfg.Centers) == 0 && len(cfg.Profile.Anchors) == 0 {
+		return nil, fmt.Errorf("config %s lists no centers or anchors", path)
+	}
+	if cfg.Serve != nil {
+		if err := cfg.Serve.validate(); err != nil {
+			return nil, fmt.Errorf("error parsing config %s: %v", path, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// applyDefaults fills in the settings data leaves out. Presence is checked on
+// the raw JSON so that explicit zero values are kept.
+func applyDefaults(cfg *Config, data []byte) error {
+	var raw struct {
+		Centers json.RawMessage            `json:"centers"`
+		Profile map[string]json.RawMessage `json:"profile"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	// The default studios are the ones the default preferences are for
+	if raw.Profile == nil {
+		cfg.Profile = corepower.DefaultProfile()
+		if raw.Centers == nil {
+			cfg.Centers = Default().Centers
+		}
+		return nil
+	}
+
+	defaults := corepower.NewProfile()
+	if _, ok := raw.Profile["category"]; !ok {
+		cfg.Profile.Category = defaults.Category
+	}
+	if _, ok := raw.Profile["mile_penalty"]; !ok {
+		cfg.Profile.MilePenalty = defaults.MilePenalty
+	}
+	horizon, ok := raw.Profile["horizon"]
+	if !ok {
+		cfg.Profile.Horizon = defaults.Horizon
+		return nil
+	}
+	var horizonFields map[string]json.RawMessage
+	if err := json.Unmarshal(horizon, &horizonFields); err != nil {
+		return err
+	}
+	if _, ok := horizonFields["max_days"]; !ok {
+		cfg.Profile.Horizon.MaxDays = defaults.Horizon.MaxDays
+	}
+	return nil
+}
+
+// CenterRefs returns the configured studios plus those within range of the
+// profile's anchors
+func (c *Config) CenterRefs() []centers.Ref {
+	refs := append([]centers.Ref(nil), c.Centers...)
+	for _, anchor := range c.Profile.Anchors {
+		if anchor.MaxMiles > 0 {
+			refs = append(refs, centers.Ref{Near: &centers.Near{Point: anchor.Point, Miles: anchor.MaxMiles}})
+		}
+	}
+	return refs
+}
+
+func (s *Serve) validate() error {
+	keys := map[string]bool{}
+	for i, user := range s.Users {
+		if user.APIKey == "" || user.Username == "" || user.Password == "" {
+			return fmt.Errorf("serve user %d needs an api_key, username and password", i+1)
+		}
+		if keys[user.APIKey] {
+			return fmt.Errorf("serve user %s reuses the API key of another user", user.Username)
+		}
+		keys[user.APIKey] = true
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/eshaanm25/corepower/internal/centers"
+	"github.com/eshaanm25/corepower/internal/corepower"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const anchor = `"anchors": [{ "name": "Home", "latitude": 30.3, "longitude": -97.7, "max_miles": 5 }]`
+
+func TestLoadDefaults(t *testing.T) {
+	defaultCenters := Default().Centers
+	tests := []struct {
+		name        string
+		data        string // Empty loads without a file
+		centers     []centers.Ref
+		category    string
+		milePenalty float64
+		horizon     corepower.Horizon
+		preferences int // Weekend and weekday preferences
+	}{
+		{
+			name:        "no file",
+			centers:     defaultCenters,
+			category:    "Yoga Sculpt",
+			milePenalty: 0.1,
+			horizon:     corepower.DefaultHorizon,
+			preferences: 8,
+		},
+		{
+			name:        "no profile or centers",
+			data:        `{"predict": {}}`,
+			centers:     defaultCenters,
+			category:    "Yoga Sculpt",
+			milePenalty: 0.1,
+			horizon:     corepower.DefaultHorizon,
+			preferences: 8,
+		},
+		{
+			name:        "centers only",
+			data:        `{"centers": [{"id": "abc"}]}`,
+			centers:     []centers.Ref{{ID: "abc"}},
+			category:    "Yoga Sculpt",
+			milePenalty: 0.1,
+			horizon:     corepower.DefaultHorizon,
+			preferences: 8,
+		},
+		{
+			name:        "profile without centers",
+			data:        `{"profile": {"weekday": [{"start": "06:00", "end": "07:00", "preference": 1}], ` + anchor + `}}`,
+			category:    "Yoga Sculpt",
+			milePenalty: 0.1,
+			horizon:     corepower.DefaultHorizon,
+			preferences: 1,
+		},
+		{
+			name:     "explicit zero mile penalty",
+			data:     `{"centers": [{"id": "abc"}], "profile": {"category": "Yoga Sculpt", "mile_penalty": 0}}`,
+			centers:  []centers.Ref{{ID: "abc"}},
+			category: "Yoga Sculpt",
+			horizon:  corepower.DefaultHorizon,
+		},
+		{
+			name:        "partial horizon",
+			data:        `{"centers": [{"id": "abc"}], "profile": {"category": "C2", "horizon": {"max_days": 7}}}`,
+			centers:     []centers.Ref{{ID: "abc"}},
+			category:    "C2",
+			milePenalty: 0.1,
+			horizon:     corepower.Horizon{MaxDays: 7},
+		},
+		{
+			name:        "horizon without max days",
+			data:        `{"centers": [{"id": "abc"}], "profile": {"horizon": {"min_days": 2}}}`,
+			centers:     []centers.Ref{{ID: "abc"}},
+			category:    "Yoga Sculpt",
+			milePenalty: 0.1,
+			horizon:     corepower.Horizon{MinDays: 2, MaxDays: 14},
+		},
+	}
+	for _, test := range tests {
+		path := ""
+		if test.data != "" {
+			path = writeConfig(t, test.data)
+		}
+		cfg, err := Load(path)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !slices.Equal(cfg.Centers, test.centers) {
+			t.Errorf("%s: got centers %v, want %v", test.name, cfg.Centers, test.centers)
+		}
+		if cfg.Profile.Category != test.category {
+			t.Errorf("%s: got category %q, want %q", test.name, cfg.Profile.Category, test.category)
+		}
+		if cfg.Profile.MilePenalty != test.milePenalty {
+			t.Errorf("%s: got mile penalty %v, want %v", test.name, cfg.Profile.MilePenalty, test.milePenalty)
+		}
+		if cfg.Profile.Horizon != test.horizon {
+			t.Errorf("%s: got horizon %+v, want %+v", test.name, cfg.Profile.Horizon, test.horizon)
+		}
+		if n := len(cfg.Profile.Weekend) + len(cfg.Profile.Weekday); n != test.preferences {
+			t.Errorf("%s: got %d preferences, want %d", test.name, n, test.preferences)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"invalid json", `{"centers": [}`, "error parsing config"},
+		{"profile without centers or anchors", `{"profile": {"category": "C2"}}`, "lists no centers or anchors"},
+		{"inverted horizon", `{"centers": [{"id": "abc"}], "profile": {"horizon": {"min_days": 9, "max_days": 7}}}`, "is after max_days"},
+		{"unknown timezone", `{"centers": [{"id": "abc"}], "profile": {"timezone": "Mars/Olympus"}}`, "error parsing config"},
+		{"serve user without key", `{"centers": [{"id": "abc"}], "serve": {"users": [{"username": "a", "password": "b"}]}}`, "needs an api_key"},
+	}
+	for _, test := range tests {
+		_, err := Load(writeConfig(t, test.data))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestLoadCalDAVPassword(t *testing.T) {
+	t.Setenv("COREPOWER_CALDAV_PASSWORD", "from-env")
+	path := writeConfig(t, `{"caldav": {"url": "https://dav.example.com/cal", "username": "me"}}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CalDAV.Password != "from-env" {
+		t.Errorf("got password %q, want it from the environment", cfg.CalDAV.Password)
+	}
+}
+
+func TestCenterRefs(t *testing.T) {
+	path := writeConfig(t, `{"centers": [{"name": "Triangle"}], "profile": {`+anchor+`}}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := cfg.CenterRefs()
+	if len(refs) != 2 || refs[0].Name != "Triangle" || refs[1].Near == nil || refs[1].Near.Miles != 5 {
+		t.Errorf("got refs %+v", refs)
+	}
+}
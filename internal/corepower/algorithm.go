@@ -1,143 +1,134 @@
 package corepower
 
 import (
+	"cmp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/geo"
 	"github.com/eshaanm25/corepower/internal/opensearch"
 )
 
+// Result is a class found by a search. Its JSON form is written by the search,
+// plan and book commands and documented in schemas/result.schema.json; fields
+// may be added but are not renamed or removed.
 type Result struct {
 	AvailableSlots    float32   `json:"available_slots"`
 	CanBook           string    `json:"can_book"`
 	CenterName        string    `json:"center_name"`
 	ClassCategoryName string    `json:"class_category_name"`
+	StartTime         time.Time `json:"start_time"`  // Local time the preferences were evaluated in
+	StudioTime        time.Time `json:"studio_time"` // Start on the studio's own clock
 	StartTimeUtc      time.Time `json:"start_time_utc"`
+	EndTimeUtc        time.Time `json:"end_time_utc"`
 	CenterID          string    `json:"center_id"`
-	SessionID         float32   `json:"session_id"`
+	SessionID         int64     `json:"session_id"`
+	Location          geo.Point `json:"location"`
+	Preference        int       `json:"preference"`     // Rank of the matched preference, lower is better
+	DistanceMiles     float64   `json:"distance_miles"` // Distance from the nearest applicable anchor
+	Adjustment        float64   `json:"adjustment"`     // Added by scorers, e.g. for classes likely to fill
+	Score             float64   `json:"score"`          // Preference plus distance penalty and adjustment, lower is better
 }
 
-type ClassPreference struct {
-	CenterName string
-	StartTime  time.Time
-	EndTime    time.Time
-	Preference int // Lower number means higher preference
+// Scorer adjusts the score of classes that matched the preferences, on top of
+// the preference and distance. Positive adjustments make a class less
+// preferred.
+type Scorer interface {
+	Adjust(class Result, now time.Time) float64
+}
+
+// NewResult converts a search result, with its start time in the profile's
+// time zone. Preference, distance and score are left for RankClasses.
+func NewResult(class opensearch.Class, profile Profile) Result {
+	return Result{
+		AvailableSlots:    class.AvailableSlots.Raw,
+		CanBook:           class.CanBook.Raw,
+		CenterName:        strings.Split(class.CenterName.Raw, ` - `)[0],
+		ClassCategoryName: class.ClassCategoryName.Raw,
+		StartTime:         profile.LocalTime(class.StartTime.Raw, class.StartTimeUtc.Raw),
+		StudioTime:        StudioTime(class.StartTime.Raw, class.StartTimeUtc.Raw),
+		StartTimeUtc:      class.StartTimeUtc.Raw,
+		EndTimeUtc:        class.EndTimeUtc.Raw,
+		CenterID:          class.CenterID.Raw,
+		SessionID:         class.SessionID.Raw,
+		Location: geo.Point{
+			Latitude:  class.CenterLocationLatitude.Raw,
+			Longitude: class.CenterLocationLongitude.Raw,
+		},
+	}
 }
 
 // FindIdealClass finds the best available class based on user preferences
-func FindIdealClass(searchResponse *opensearch.SearchResponse) *Result {
-	// Get Central Time location
-	centralTime, _ := time.LoadLocation("America/Chicago")
+func FindIdealClass(searchResponse *opensearch.SearchResponse, profile Profile, clk clock.Clock) *Result {
+	ranked := RankClasses(searchResponse, profile, clk)
+	if len(ranked) == 0 {
+		return nil
+	}
+	return &ranked[0]
+}
+
+// RankClasses returns every available class within the booking horizon that
+// matches a preference, is close enough to the profile's anchors and does not
+// clash with the calendar, best first. Scorers adjust the score of each
+// matching class. Classes with the same score keep their search order.
+func RankClasses(searchResponse *opensearch.SearchResponse, profile Profile, clk clock.Clock, scorers ...Scorer) []Result {
+	now := clk.Now()
 
-	// Filter for Yoga Sculpt classes 14 days from now that are available and bookable
+	// Filter for classes within the horizon that are available and bookable
 	var validClasses []Result
 	for _, class := range searchResponse.Results {
-		if class.ClassCategoryName.Raw == "Yoga Sculpt" &&
+		if class.ClassCategoryName.Raw == profile.Category &&
 			class.CanBook.Raw == "true" &&
 			class.Status.Raw == 2 &&
 			class.AvailableSlots.Raw > 0 &&
-			class.StartTimeUtc.Raw.After(time.Now().AddDate(0, 0, 13)) {
-			result := Result{
-				AvailableSlots:    class.AvailableSlots.Raw,
-				CanBook:           class.CanBook.Raw,
-				CenterName:        strings.Split(class.CenterName.Raw, ` - `)[0],
-				ClassCategoryName: class.ClassCategoryName.Raw,
-				StartTimeUtc:      class.StartTimeUtc.Raw,
-				CenterID:          class.CenterID.Raw,
-				SessionID:         class.SessionID.Raw,
-			}
-			validClasses = append(validClasses, result)
+			profile.Horizon.Contains(now, class.StartTimeUtc.Raw) {
+			validClasses = append(validClasses, NewResult(class, profile))
 		}
 	}
 
-	var preferences []ClassPreference
-	var bestClass *Result
-	bestPreference := 999
-
+	var ranked []Result
 	for _, class := range validClasses {
-		// Convert UTC class time to Central Time for comparison
-		classTimeCT := class.StartTimeUtc.In(centralTime)
-		weekday := classTimeCT.Weekday()
-		isWeekend := weekday == time.Saturday || weekday == time.Sunday || weekday == time.Monday || weekday == time.Friday
+		// Compare in the profile's time zone, or the studio's
+		classTime := class.StartTime
+		classTimeOfDay := TimeOfDayOf(classTime)
 
-		if isWeekend {
-			// Weekend preferences
-			preferences = []ClassPreference{
-				{
-					CenterName: "Monarch",
-					StartTime:  time.Date(0, 1, 1, 13, 0, 0, 0, centralTime), // 1:00 PM CT
-					EndTime:    time.Date(0, 1, 1, 15, 0, 0, 0, centralTime), // 3:00 PM CT
-					Preference: 1,
-				},
-				{
-					CenterName: "Mueller",
-					StartTime:  time.Date(0, 1, 1, 13, 0, 0, 0, centralTime), // 1:00 PM CT
-					EndTime:    time.Date(0, 1, 1, 15, 0, 0, 0, centralTime), // 3:00 PM CT
-					Preference: 2,
-				},
-				{
-					CenterName: "Triangle",
-					StartTime:  time.Date(0, 1, 1, 11, 0, 0, 0, centralTime), // 11:00 AM CT
-					EndTime:    time.Date(0, 1, 1, 15, 0, 0, 0, centralTime), // 3:00 PM CT
-					Preference: 2,
-				},
-				{
-					CenterName: "Cedar Park",
-					StartTime:  time.Date(0, 1, 1, 12, 0, 0, 0, centralTime), // 12:00 PM CT
-					EndTime:    time.Date(0, 1, 1, 17, 0, 0, 0, centralTime), // 5:00 PM CT
-					Preference: 3,
-				},
-			}
-		} else {
-			// Weekday preferences
-			preferences = []ClassPreference{
-				{
-					CenterName: "Triangle",
-					StartTime:  time.Date(0, 1, 1, 17, 30, 0, 0, centralTime), // 5:30 PM CT
-					EndTime:    time.Date(0, 1, 1, 18, 30, 0, 0, centralTime), // 6:30 PM CT
-					Preference: 1,
-				},
-				{
-					CenterName: "Mueller",
-					StartTime:  time.Date(0, 1, 1, 18, 0, 0, 0, centralTime),  // 6:00 PM CT
-					EndTime:    time.Date(0, 1, 1, 18, 45, 0, 0, centralTime), // 6:45 PM CT
-					Preference: 2,
-				},
-				{
-					CenterName: "Monarch",
-					StartTime:  time.Date(0, 1, 1, 18, 0, 0, 0, centralTime),  // 6:00 PM CT
-					EndTime:    time.Date(0, 1, 1, 18, 45, 0, 0, centralTime), // 6:45 PM CT
-					Preference: 2,
-				},
-				{
-					CenterName: "Cedar Park",
-					StartTime:  time.Date(0, 1, 1, 18, 30, 0, 0, centralTime), // 6:30 PM CT
-					EndTime:    time.Date(0, 1, 1, 19, 30, 0, 0, centralTime), // 7:30 PM CT
-					Preference: 3,
-				},
-			}
+		// Skip classes during busy time
+		end := class.EndTimeUtc
+		if end.IsZero() {
+			end = class.StartTimeUtc.Add(time.Hour)
+		}
+		if _, busy := profile.Conflict(class.StartTimeUtc, end); busy {
+			continue
 		}
 
-		// Get class time of day for comparison (in Central Time)
-		classTimeOfDay := time.Date(0, 1, 1,
-			classTimeCT.Hour(),
-			classTimeCT.Minute(),
-			0, 0, centralTime)
+		// Skip studios too far from where the user is that day
+		distance, ok := profile.Distance(classTime.Weekday(), class.Location)
+		if !ok {
+			continue
+		}
 
 		// Check against preferences
-		for _, pref := range preferences {
+		for _, pref := range profile.PreferencesFor(classTime.Weekday()) {
 			if strings.Contains(strings.ToLower(class.CenterName), strings.ToLower(pref.CenterName)) &&
-				!classTimeOfDay.Before(pref.StartTime) &&
-				!classTimeOfDay.After(pref.EndTime) {
-				if pref.Preference < bestPreference {
-					bestPreference = pref.Preference
-					classCopy := class
-					bestClass = &classCopy
+				classTimeOfDay >= pref.StartTime &&
+				classTimeOfDay <= pref.EndTime {
+				class.Preference = pref.Preference
+				class.DistanceMiles = distance
+				for _, scorer := range scorers {
+					class.Adjustment += scorer.Adjust(class, now)
 				}
+				class.Score = float64(pref.Preference) + distance*profile.MilePenalty + class.Adjustment
+				ranked = append(ranked, class)
 				break
 			}
 		}
 	}
 
-	return bestClass
+	slices.SortStableFunc(ranked, func(a, b Result) int {
+		return cmp.Compare(a.Score, b.Score)
+	})
+
+	return ranked
 }
@@ -0,0 +1,292 @@
+package corepower
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/opensearch"
+)
+
+// testClass returns a bookable class of the default category. start is the
+// UTC start time; the studio wall clock is derived from it in zone.
+func testClass(center string, start time.Time, zone *time.Location) opensearch.Class {
+	var class opensearch.Class
+	class.CenterName.Raw = center + " - Austin"
+	class.CenterID.Raw = center
+	class.ClassCategoryName.Raw = "Yoga Sculpt"
+	class.CanBook.Raw = "true"
+	class.Status.Raw = 2
+	class.AvailableSlots.Raw = 5
+	class.StartTimeUtc.Raw = start
+	class.EndTimeUtc.Raw = start.Add(time.Hour)
+	wall := start.In(zone)
+	class.StartTime.Raw = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, time.UTC)
+	return class
+}
+
+func TestHorizonWindow(t *testing.T) {
+	zone := chicago(t)
+	tests := []struct {
+		name     string
+		horizon  Horizon
+		now      time.Time
+		from, to time.Time
+	}{
+		{
+			name:    "newest day only",
+			horizon: DefaultHorizon,
+			now:     utc("2026-10-19T05:00:00Z"),
+			from:    utc("2026-11-01T05:00:00Z"),
+			to:      utc("2026-11-02T05:00:00Z"),
+		},
+		{
+			name:    "min and max days",
+			horizon: Horizon{MinDays: 1, MaxDays: 7},
+			now:     utc("2026-10-19T05:00:00Z"),
+			from:    utc("2026-10-20T05:00:00Z"),
+			to:      utc("2026-10-26T05:00:00Z"),
+		},
+		{
+			// Days are calendar days in the clock's zone, so midnight stays
+			// midnight across fall back and the window grows by an hour
+			name:    "newest day across fall back",
+			horizon: DefaultHorizon,
+			now:     time.Date(2026, 10, 19, 0, 0, 0, 0, zone),
+			from:    time.Date(2026, 11, 1, 0, 0, 0, 0, zone),
+			to:      time.Date(2026, 11, 2, 0, 0, 0, 0, zone),
+		},
+		{
+			name:    "newest day across spring forward",
+			horizon: DefaultHorizon,
+			now:     time.Date(2026, 2, 23, 0, 0, 0, 0, zone),
+			from:    time.Date(2026, 3, 8, 0, 0, 0, 0, zone),
+			to:      time.Date(2026, 3, 9, 0, 0, 0, 0, zone),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(tt.now)
+			from, to := tt.horizon.Window(clk.Now())
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Fatalf("Window = [%v, %v), want [%v, %v)", from, to, tt.from, tt.to)
+			}
+
+			// The window is closed at from and open at to
+			for _, boundary := range []struct {
+				start time.Time
+				want  bool
+			}{
+				{from.Add(-time.Nanosecond), false},
+				{from, true},
+				{to.Add(-time.Nanosecond), true},
+				{to, false},
+			} {
+				if got := tt.horizon.Contains(clk.Now(), boundary.start); got != boundary.want {
+					t.Errorf("Contains(%v) = %v, want %v", boundary.start, got, boundary.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRankClasses(t *testing.T) {
+	zone := chicago(t)
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, zone).UTC()
+	}
+
+	// Defaults: Saturday, Sunday, Monday and Friday use the weekend windows
+	profile := DefaultProfile()
+	if err := profile.LoadLocation(); err != nil {
+		t.Fatal(err)
+	}
+	profile.Horizon = Horizon{MaxDays: 14}
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		class  func() opensearch.Class
+		want   int // Matched preference, 0 when the class is not ranked
+		studio bool
+	}{
+		{
+			name:  "weekday window",
+			now:   at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class { return testClass("Triangle", at(2026, 10, 20, 17, 30), zone) },
+			want:  1,
+		},
+		{
+			name:  "weekday window end is inclusive",
+			now:   at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class { return testClass("Triangle", at(2026, 10, 20, 18, 30), zone) },
+			want:  1,
+		},
+		{
+			name:  "after the weekday window",
+			now:   at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class { return testClass("Triangle", at(2026, 10, 20, 18, 31), zone) },
+		},
+		{
+			name:  "weekend window on a weekday",
+			now:   at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class { return testClass("Monarch", at(2026, 10, 20, 13, 0), zone) },
+		},
+		{
+			name:  "weekend window on saturday",
+			now:   at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class { return testClass("Monarch", at(2026, 10, 24, 13, 0), zone) },
+			want:  1,
+		},
+		{
+			name:  "monday counts as weekend",
+			now:   at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class { return testClass("Mueller", at(2026, 10, 26, 14, 0), zone) },
+			want:  2,
+		},
+		{
+			// 6:00 PM CST is midnight UTC; read with the summer offset it
+			// would be 7:00 PM and miss the window
+			name:  "after fall back",
+			now:   at(2026, 10, 26, 0, 0),
+			class: func() opensearch.Class { return testClass("Mueller", at(2026, 11, 3, 18, 0), zone) },
+			want:  2,
+		},
+		{
+			name:  "before fall back",
+			now:   at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class { return testClass("Mueller", at(2026, 10, 29, 18, 45), zone) },
+			want:  2,
+		},
+		{
+			// 5:30 PM CDT is 22:30 UTC, which in winter time would read 4:30 PM
+			name:  "after spring forward",
+			now:   at(2026, 3, 2, 0, 0),
+			class: func() opensearch.Class { return testClass("Triangle", at(2026, 3, 10, 17, 30), zone) },
+			want:  1,
+		},
+		{
+			name: "studio zone after spring forward",
+			now:  at(2026, 3, 2, 0, 0),
+			class: func() opensearch.Class {
+				return testClass("Triangle", at(2026, 3, 10, 17, 30), zone)
+			},
+			want:   1,
+			studio: true,
+		},
+		{
+			name:  "outside the horizon",
+			now:   at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class { return testClass("Triangle", at(2026, 11, 3, 17, 30), zone) },
+		},
+		{
+			name:  "already started",
+			now:   at(2026, 10, 20, 17, 31),
+			class: func() opensearch.Class { return testClass("Triangle", at(2026, 10, 20, 17, 30), zone) },
+		},
+		{
+			name: "full",
+			now:  at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class {
+				class := testClass("Triangle", at(2026, 10, 20, 17, 30), zone)
+				class.AvailableSlots.Raw = 0
+				return class
+			},
+		},
+		{
+			name: "cancelled",
+			now:  at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class {
+				class := testClass("Triangle", at(2026, 10, 20, 17, 30), zone)
+				class.Status.Raw = 3
+				return class
+			},
+		},
+		{
+			name: "other category",
+			now:  at(2026, 10, 19, 0, 0),
+			class: func() opensearch.Class {
+				class := testClass("Triangle", at(2026, 10, 20, 17, 30), zone)
+				class.ClassCategoryName.Raw = "C2 Yoga"
+				return class
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := profile
+			if tt.studio {
+				profile.Timezone, profile.Location = "", nil
+			}
+			res := &opensearch.SearchResponse{Results: []opensearch.Class{tt.class()}}
+			ranked := RankClasses(res, profile, clock.NewFake(tt.now))
+
+			if tt.want == 0 {
+				if len(ranked) != 0 {
+					t.Fatalf("ranked %+v, want nothing", ranked)
+				}
+				return
+			}
+			if len(ranked) != 1 {
+				t.Fatalf("ranked %d classes, want 1", len(ranked))
+			}
+			if ranked[0].Preference != tt.want {
+				t.Errorf("preference = %d, want %d", ranked[0].Preference, tt.want)
+			}
+		})
+	}
+}
+
+type fixedScorer map[string]float64
+
+func (s fixedScorer) Adjust(class Result, now time.Time) float64 {
+	return s[class.CenterID]
+}
+
+func TestRankClassesOrder(t *testing.T) {
+	zone := chicago(t)
+	clk := clock.NewFake(time.Date(2026, 10, 19, 0, 0, 0, 0, zone))
+	saturday := func(hour int) time.Time { return time.Date(2026, 10, 24, hour, 0, 0, 0, zone).UTC() }
+
+	profile := DefaultProfile()
+	if err := profile.LoadLocation(); err != nil {
+		t.Fatal(err)
+	}
+	profile.Horizon = Horizon{MaxDays: 14}
+
+	res := &opensearch.SearchResponse{Results: []opensearch.Class{
+		testClass("Cedar Park", saturday(16), zone), // Preference 3
+		testClass("Triangle", saturday(11), zone),   // Preference 2
+		testClass("Mueller", saturday(14), zone),    // Preference 2, after Triangle in search order
+		testClass("Monarch", saturday(14), zone),    // Preference 1
+	}}
+
+	tests := []struct {
+		name    string
+		scorers []Scorer
+		want    []string
+	}{
+		{"by preference, ties in search order", nil, []string{"Monarch", "Triangle", "Mueller", "Cedar Park"}},
+		{"scorer adjustments", []Scorer{fixedScorer{"Monarch": 1.5}}, []string{"Triangle", "Mueller", "Monarch", "Cedar Park"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := RankClasses(res, profile, clk, tt.scorers...)
+			var got []string
+			for _, class := range ranked {
+				got = append(got, class.CenterName)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ranked %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ranked %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package corepower
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/ical"
+)
+
+// Start returns the class start time
+func (r ReservationResponse) Start() (time.Time, error) {
+	return parseAPITime(r.StartTimeUTC)
+}
+
+// End returns the class end time
+func (r ReservationResponse) End() (time.Time, error) {
+	return parseAPITime(r.EndTimeUTC)
+}
+
+// Cancelled reports whether the reservation has been cancelled, either by the
+// member or by the studio cancelling the class
+func (r ReservationResponse) Cancelled() bool {
+	return r.RegistrationStatus == RegistrationCancelled || strings.EqualFold(r.Status, "Cancelled")
+}
+
+// Waitlisted reports whether the reservation is a waitlist spot rather than a
+// booking. The API sends a waitlist position of 0 or null for bookings.
+func (r ReservationResponse) Waitlisted() bool {
+	if r.RegistrationStatus == RegistrationWaitlisted || strings.EqualFold(r.Status, "Waitlisted") {
+		return true
+	}
+	switch position := r.CurrentWaitlistPosition.(type) {
+	case float64:
+		return position > 0
+	case int:
+		return position > 0
+	case string:
+		n, err := strconv.Atoi(position)
+		return err == nil && n > 0
+	}
+	return false
+}
+
+// UID is a calendar UID that stays the same for the life of the reservation
+func (r ReservationResponse) UID() string {
+	return fmt.Sprintf("reservation-%d@corepoweryoga.com", r.ID)
+}
+
+// Event converts the reservation into a calendar event
+func (r ReservationResponse) Event() (ical.Event, error) {
+	start, err := r.Start()
+	if err != nil {
+		return ical.Event{}, fmt.Errorf("reservation %d: %v", r.ID, err)
+	}
+	end, err := r.End()
+	if err != nil {
+		return ical.Event{}, fmt.Errorf("reservation %d: %v", r.ID, err)
+	}
+
+	summary := r.ClassName
+	if summary == "" {
+		summary = r.SessionName
+	}
+
+	var description []string
+	if r.Instructor != "" {
+		description = append(description, "Instructor: "+r.Instructor)
+	}
+	if r.IsInstructorSubstituted {
+		description = append(description, "Instructor substituted")
+	}
+	if r.IsVirtualClass && r.StudentVirtualLink != "" {
+		description = append(description, "Join: "+r.StudentVirtualLink)
+	}
+
+	event := ical.Event{
+		UID:         r.UID(),
+		Summary:     summary,
+		Location:    r.Center,
+		Description: strings.Join(description, "\n"),
+		Start:       start,
+		End:         end,
+		Status:      "CONFIRMED",
+	}
+	if r.Cancelled() {
+		event.Status = "CANCELLED"
+	}
+	return event, nil
+}
+
+// parseAPITime parses the API's UTC timestamps, which may or may not carry a
+// zone designator
+func parseAPITime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
@@ -0,0 +1,43 @@
+package corepower
+
+import "testing"
+
+func TestReservationCancelled(t *testing.T) {
+	tests := []struct {
+		name        string
+		reservation ReservationResponse
+		want        bool
+	}{
+		{"booked", ReservationResponse{RegistrationStatus: RegistrationBooked, Status: "Reserved"}, false},
+		{"waitlisted", ReservationResponse{RegistrationStatus: RegistrationWaitlisted, Status: "Waitlisted"}, false},
+		{"cancelled registration", ReservationResponse{RegistrationStatus: RegistrationCancelled}, true},
+		{"class cancelled by the studio", ReservationResponse{RegistrationStatus: RegistrationBooked, Status: "Cancelled"}, true},
+		{"status merely mentioning cancel", ReservationResponse{RegistrationStatus: RegistrationBooked, Status: "Late cancel fee waived"}, false},
+	}
+	for _, test := range tests {
+		if got := test.reservation.Cancelled(); got != test.want {
+			t.Errorf("%s: Cancelled() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestReservationWaitlisted(t *testing.T) {
+	tests := []struct {
+		name        string
+		reservation ReservationResponse
+		want        bool
+	}{
+		{"booked without position", ReservationResponse{RegistrationStatus: RegistrationBooked, Status: "Reserved"}, false},
+		{"booked with position 0", ReservationResponse{RegistrationStatus: RegistrationBooked, CurrentWaitlistPosition: float64(0)}, false},
+		{"booked with empty position", ReservationResponse{RegistrationStatus: RegistrationBooked, CurrentWaitlistPosition: ""}, false},
+		{"position from JSON", ReservationResponse{CurrentWaitlistPosition: float64(3)}, true},
+		{"position as text", ReservationResponse{CurrentWaitlistPosition: "2"}, true},
+		{"waitlisted registration", ReservationResponse{RegistrationStatus: RegistrationWaitlisted}, true},
+		{"waitlisted status", ReservationResponse{Status: "Waitlisted"}, true},
+	}
+	for _, test := range tests {
+		if got := test.reservation.Waitlisted(); got != test.want {
+			t.Errorf("%s: Waitlisted() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
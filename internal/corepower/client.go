@@ -2,15 +2,22 @@ package corepower
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/retry"
 )
 
 var (
 	reservationEndpoint = "https://api2.corepoweryoga.com"
+	defaultTimeout      = 30 * time.Second
 )
 
 type headerTransport struct {
@@ -26,16 +33,28 @@ func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 }
 
 type Reservation struct {
-	CenterId  string  `json:"centerId"`
-	SessionId float32 `json:"sessionId"`
+	CenterId  string `json:"centerId"`
+	SessionId int64  `json:"sessionId"`
 }
 
 type Client struct {
 	Endpoint          *url.URL
 	ReservationClient *http.Client
 	Token             string
+	Timeout           time.Duration // Deadline for a single request, defaults to 30s
+	Retry             retry.Policy
 }
 
+// Values of ReservationResponse.RegistrationStatus
+const (
+	RegistrationBooked     = 1
+	RegistrationWaitlisted = 2
+	RegistrationCancelled  = 3
+)
+
+// ReservationResponse is a reservation as returned by the CorePower API. The
+// list and book commands write it unchanged, see
+// schemas/reservation.schema.json.
 type ReservationResponse struct {
 	ID                 int    `json:"id"`
 	StartTime          string `json:"startTime"`
@@ -59,7 +78,7 @@ type ReservationResponse struct {
 	CurrentWaitlistPosition  any    `json:"currentWaitlistPosition"`
 	ClassType                int    `json:"classType"`
 	ClassID                  int    `json:"classId"`
-	SessionID                int    `json:"sessionId"`
+	SessionID                int64  `json:"sessionId"`
 	CenterID                 string `json:"centerId"`
 	StudentVirtualLink       string `json:"studentVirtualLink"`
 	IsVirtualClass           bool   `json:"isVirtualClass"`
@@ -73,7 +92,13 @@ type ReservationResponse struct {
 
 func (c *Client) Initialize() {
 	c.Endpoint, _ = url.Parse(reservationEndpoint)
-	c.ReservationClient = &http.Client{}
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+	c.ReservationClient = &http.Client{Timeout: c.Timeout}
+	if c.Retry.MaxAttempts == 0 {
+		c.Retry = retry.DefaultPolicy
+	}
 
 	c.ReservationClient.Transport = &headerTransport{
 		base: http.DefaultTransport,
@@ -83,7 +108,129 @@ func (c *Client) Initialize() {
 	}
 }
 
-func (c *Client) Reserve(centerId string, sessionId float32) (ReservationResponse, error) {
+// Reserve books a spot in the given session. Failed attempts are retried, but
+// only after checking the account's reservations so that a request which went
+// through without us seeing the response is not booked twice.
+func (c *Client) Reserve(ctx context.Context, centerId string, sessionId int64) (ReservationResponse, error) {
+	var reservation ReservationResponse
+	attempt := 0
+	err := c.Retry.Do(ctx, func(ctx context.Context) error {
+		attempt++
+		if attempt > 1 {
+			existing, err := c.findReservation(ctx, sessionId)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				slog.InfoContext(ctx, "Found reservation made by an earlier attempt", "reservation_id", existing.ID)
+				reservation = *existing
+				return nil
+			}
+		}
+		slog.DebugContext(ctx, "Sending reservation request", "center_id", centerId, "session_id", sessionId, "attempt", attempt)
+
+		var err error
+		reservation, err = c.reserve(ctx, centerId, sessionId)
+		return err
+	})
+	if err != nil {
+		return ReservationResponse{}, err
+	}
+
+	return reservation, nil
+}
+
+// Reservations lists the upcoming reservations for the authenticated user
+func (c *Client) Reservations(ctx context.Context) ([]ReservationResponse, error) {
+	var reservations []ReservationResponse
+	err := c.Retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		reservations, err = c.reservations(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// Cancel cancels a reservation. A retried request that finds the reservation
+// gone assumes an earlier attempt cancelled it.
+func (c *Client) Cancel(ctx context.Context, reservationID int) error {
+	attempt := 0
+	return c.Retry.Do(ctx, func(ctx context.Context) error {
+		attempt++
+		slog.DebugContext(ctx, "Sending cancellation request", "reservation_id", reservationID, "attempt", attempt)
+
+		err := c.cancel(ctx, reservationID)
+		var apiErr *APIError
+		if attempt > 1 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			slog.InfoContext(ctx, "Reservation already gone, assuming an earlier attempt cancelled it", "reservation_id", reservationID)
+			return nil
+		}
+		return err
+	})
+}
+
+// findReservation returns the existing reservation for a session, or nil if
+// there is none
+func (c *Client) findReservation(ctx context.Context, sessionId int64) (*ReservationResponse, error) {
+	reservations, err := c.reservations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error checking existing reservations: %w", err)
+	}
+
+	for _, reservation := range reservations {
+		if reservation.SessionID == sessionId && !reservation.Cancelled() {
+			return &reservation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *Client) reservations(ctx context.Context) ([]ReservationResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	baseURL := *c.Endpoint
+	baseURL.Path = "/reservation"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("x-api-version", "2.0")
+
+	resp, err := c.ReservationClient.Do(req)
+	if err != nil {
+		return nil, retry.Retryable(fmt.Errorf("error making request: %v", err), 0)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, retry.Retryable(fmt.Errorf("error reading response: %v", err), 0)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.Retry.FromResponse(resp, newAPIError(resp.StatusCode, body))
+	}
+
+	var reservations []ReservationResponse
+	err = json.Unmarshal(body, &reservations)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %v", err)
+	}
+
+	return reservations, nil
+}
+
+// reserve sends a single reservation request
+func (c *Client) reserve(ctx context.Context, centerId string, sessionId int64) (ReservationResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
 
 	reservation := Reservation{
 		CenterId:  centerId,
@@ -94,10 +241,10 @@ func (c *Client) Reserve(centerId string, sessionId float32) (ReservationRespons
 		return ReservationResponse{}, fmt.Errorf("error marshaling reservation: %v", err)
 	}
 
-	baseURL := c.Endpoint
+	baseURL := *c.Endpoint
 	baseURL.Path = "/reservation"
 
-	req, err := http.NewRequest("POST", baseURL.String(), bytes.NewBuffer(payload))
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL.String(), bytes.NewBuffer(payload))
 	if err != nil {
 		return ReservationResponse{}, fmt.Errorf("error creating request: %v", err)
 	}
@@ -107,17 +254,17 @@ func (c *Client) Reserve(centerId string, sessionId float32) (ReservationRespons
 
 	resp, err := c.ReservationClient.Do(req)
 	if err != nil {
-		return ReservationResponse{}, fmt.Errorf("error making request: %v", err)
+		return ReservationResponse{}, retry.Retryable(fmt.Errorf("error making request: %v", err), 0)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return ReservationResponse{}, fmt.Errorf("error reading response: %v", err)
+		return ReservationResponse{}, retry.Retryable(fmt.Errorf("error reading response: %v", err), 0)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return ReservationResponse{}, fmt.Errorf("error: received status code %d", resp.StatusCode)
+		return ReservationResponse{}, c.Retry.FromResponse(resp, newAPIError(resp.StatusCode, body))
 	}
 
 	var reservationResponse ReservationResponse
@@ -128,3 +275,36 @@ func (c *Client) Reserve(centerId string, sessionId float32) (ReservationRespons
 
 	return reservationResponse, nil
 }
+
+// cancel sends a single cancellation request
+func (c *Client) cancel(ctx context.Context, reservationID int) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	baseURL := *c.Endpoint
+	baseURL.Path = fmt.Sprintf("/reservation/%d", reservationID)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("x-api-version", "2.0")
+
+	resp, err := c.ReservationClient.Do(req)
+	if err != nil {
+		return retry.Retryable(fmt.Errorf("error making request: %v", err), 0)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retry.Retryable(fmt.Errorf("error reading response: %v", err), 0)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.Retry.FromResponse(resp, newAPIError(resp.StatusCode, body))
+	}
+
+	return nil
+}
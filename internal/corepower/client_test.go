@@ -0,0 +1,140 @@
+package corepower
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/retry"
+)
+
+// step is a request the fake API expects and how it answers
+type step struct {
+	method string
+	status int
+	body   string
+}
+
+// scriptedServer answers requests with the given steps in order, failing the
+// test on any request it does not expect
+func scriptedServer(t *testing.T, steps ...step) (*Client, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Method+" "+r.URL.Path+" "+string(body))
+		if len(steps) == 0 {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		next := steps[0]
+		steps = steps[1:]
+		if r.Method != next.method {
+			t.Errorf("got %s %s, want %s", r.Method, r.URL.Path, next.method)
+		}
+		w.WriteHeader(next.status)
+		io.WriteString(w, next.body)
+	}))
+	t.Cleanup(func() {
+		server.Close()
+		if len(steps) > 0 {
+			t.Errorf("%d expected requests were not made", len(steps))
+		}
+	})
+
+	endpoint, _ := url.Parse(server.URL)
+	client := &Client{
+		Endpoint:          endpoint,
+		ReservationClient: server.Client(),
+		Timeout:           5 * time.Second,
+		// No backoff, so the fake clock never has to be advanced
+		Retry: retry.Policy{MaxAttempts: 4, Clock: clock.NewFake(time.Now())},
+	}
+	return client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies
+	}
+}
+
+// A session ID a float32 cannot hold exactly
+const sessionID = 123456789
+
+func TestReserveRetryFindsEarlierReservation(t *testing.T) {
+	client, requests := scriptedServer(t,
+		step{"POST", http.StatusServiceUnavailable, `upstream unavailable`},
+		// A cancelled registration for the same session is not a booking
+		step{"GET", http.StatusOK, `[{"id":1,"sessionId":123456789,"registrationStatus":3},{"id":2,"sessionId":7,"registrationStatus":1}]`},
+		step{"POST", http.StatusBadGateway, `bad gateway`},
+		step{"GET", http.StatusOK, `[{"id":1,"sessionId":123456789,"registrationStatus":3},{"id":900,"sessionId":123456789,"registrationStatus":1}]`},
+	)
+
+	reservation, err := client.Reserve(context.Background(), "c1", sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reservation.ID != 900 {
+		t.Errorf("got reservation %d, want 900", reservation.ID)
+	}
+	if got, want := requests()[0], `POST /reservation {"centerId":"c1","sessionId":123456789}`; got != want {
+		t.Errorf("got request %s, want %s", got, want)
+	}
+}
+
+func TestReserveRetryBooksWhenNotFound(t *testing.T) {
+	client, _ := scriptedServer(t,
+		step{"POST", http.StatusInternalServerError, ``},
+		step{"GET", http.StatusOK, `[]`},
+		step{"POST", http.StatusOK, `{"id":901,"sessionId":123456789,"registrationStatus":1}`},
+	)
+
+	reservation, err := client.Reserve(context.Background(), "c1", sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reservation.ID != 901 || reservation.SessionID != sessionID {
+		t.Errorf("got reservation %d for session %d, want 901 for %d", reservation.ID, reservation.SessionID, sessionID)
+	}
+}
+
+func TestReserveDoesNotRetryRejection(t *testing.T) {
+	client, _ := scriptedServer(t,
+		step{"POST", http.StatusBadRequest, `{"code":"CLASS_FULL","message":"This class is full"}`},
+	)
+
+	_, err := client.Reserve(context.Background(), "c1", sessionID)
+	if !errors.Is(err, ErrClassFull) {
+		t.Errorf("got error %v, want ErrClassFull", err)
+	}
+}
+
+func TestCancelRetry(t *testing.T) {
+	// Gone on the retry, so the first attempt went through
+	client, _ := scriptedServer(t,
+		step{"DELETE", http.StatusServiceUnavailable, ``},
+		step{"DELETE", http.StatusNotFound, ``},
+	)
+	if err := client.Cancel(context.Background(), 900); err != nil {
+		t.Errorf("got error %v", err)
+	}
+
+	// Gone on the first attempt, so there was nothing to cancel
+	client, _ = scriptedServer(t,
+		step{"DELETE", http.StatusNotFound, ``},
+	)
+	var apiErr *APIError
+	if err := client.Cancel(context.Background(), 900); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got error %v, want a 404", err)
+	}
+}
@@ -0,0 +1,149 @@
+package corepower
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrClassFull            = errors.New("class is full")
+	ErrAlreadyReserved      = errors.New("class is already reserved")
+	ErrUnauthorized         = errors.New("not authorized")
+	ErrOutsideBookingWindow = errors.New("class is outside the booking window")
+	ErrNotBookable          = errors.New("membership does not cover this class")
+)
+
+// APIError is returned when the CorePower API responds with a non-200 status
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("error: received status code %d", e.StatusCode)
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is matches the error against the sentinel errors so callers can use
+// errors.Is(err, ErrClassFull) and friends
+func (e *APIError) Is(target error) bool {
+	if target == ErrUnauthorized {
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return e.kind() == target
+}
+
+// errorCodes maps the error codes sent by the API, normalized by
+// normalizeCode, to sentinel errors
+var errorCodes = map[string]error{
+	"CLASS_FULL":               ErrClassFull,
+	"SESSION_FULL":             ErrClassFull,
+	"CLASS_AT_CAPACITY":        ErrClassFull,
+	"ALREADY_RESERVED":         ErrAlreadyReserved,
+	"ALREADY_BOOKED":           ErrAlreadyReserved,
+	"ALREADY_REGISTERED":       ErrAlreadyReserved,
+	"DUPLICATE_RESERVATION":    ErrAlreadyReserved,
+	"OUTSIDE_BOOKING_WINDOW":   ErrOutsideBookingWindow,
+	"BOOKING_WINDOW_CLOSED":    ErrOutsideBookingWindow,
+	"BOOKING_WINDOW_NOT_OPEN":  ErrOutsideBookingWindow,
+	"NOT_ELIGIBLE":             ErrNotBookable,
+	"NO_PRICING_OPTION":        ErrNotBookable,
+	"MEMBERSHIP_REQUIRED":      ErrNotBookable,
+	"MEMBERSHIP_LIMIT":         ErrNotBookable,
+	"MEMBERSHIP_LIMIT_REACHED": ErrNotBookable,
+}
+
+// errorKeywords classifies errors without a known code by their message.
+// Order matters: a membership limit message that says "already" must not
+// read as an existing reservation, so not bookable is checked first.
+var errorKeywords = []struct {
+	err      error
+	keywords []string
+}{
+	{ErrNotBookable, []string{"membership", "not eligible", "not bookable", "pricing option", "purchase"}},
+	{ErrOutsideBookingWindow, []string{"booking window", "not yet open", "too early", "too far", "in advance"}},
+	{ErrClassFull, []string{"is full", "at capacity", "no spots", "no available spots"}},
+	{ErrAlreadyReserved, []string{"already reserved", "already booked", "already registered", "already have a reservation"}},
+}
+
+// kind returns the sentinel error the API error stands for, nil when it is
+// not recognized. The error code decides when it is known, the message is
+// only a fallback.
+func (e *APIError) kind() error {
+	if err, ok := errorCodes[normalizeCode(e.Code)]; ok {
+		return err
+	}
+	text := strings.ToLower(e.Code + " " + e.Message)
+	for _, class := range errorKeywords {
+		for _, keyword := range class.keywords {
+			if strings.Contains(text, keyword) {
+				return class.err
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeCode turns codes like "ClassFull", "class-full" or "CLASS_FULL"
+// into the CLASS_FULL form used by errorCodes
+func normalizeCode(code string) string {
+	var b strings.Builder
+	for i, r := range code {
+		switch {
+		case r == '-' || r == ' ' || r == '.':
+			r = '_'
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(rune(code[i-1])):
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// errorBody covers the shapes of error payloads returned by the API
+type errorBody struct {
+	Code      any    `json:"code"`
+	ErrorCode any    `json:"errorCode"`
+	Message   string `json:"message"`
+	Error     string `json:"error"`
+	Title     string `json:"title"`
+	Detail    string `json:"detail"`
+}
+
+// newAPIError builds an APIError from a response status and body
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+
+	var decoded errorBody
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		// Not JSON, keep whatever text the server sent
+		apiErr.Message = strings.TrimSpace(string(body))
+		return apiErr
+	}
+
+	for _, code := range []any{decoded.Code, decoded.ErrorCode} {
+		if code != nil {
+			apiErr.Code = fmt.Sprint(code)
+			break
+		}
+	}
+	for _, message := range []string{decoded.Message, decoded.Detail, decoded.Error, decoded.Title} {
+		if message != "" {
+			apiErr.Message = message
+			break
+		}
+	}
+
+	return apiErr
+}
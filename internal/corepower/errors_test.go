@@ -0,0 +1,59 @@
+package corepower
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	sentinels := []error{ErrClassFull, ErrAlreadyReserved, ErrUnauthorized, ErrOutsideBookingWindow, ErrNotBookable}
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error // nil when no sentinel should match
+	}{
+		{"full by code", http.StatusBadRequest, `{"code":"CLASS_FULL","message":"This class is full. Join the waitlist?"}`, ErrClassFull},
+		{"full by camel case code", http.StatusConflict, `{"errorCode":"ClassFull","message":"Unable to reserve"}`, ErrClassFull},
+		{"already reserved by code", http.StatusConflict, `{"code":"ALREADY_RESERVED","message":"You already have a reservation for this class"}`, ErrAlreadyReserved},
+		{"membership limit mentioning already", http.StatusConflict, `{"message":"You have already reached your membership limit for this week"}`, ErrNotBookable},
+		{"membership limit by code", http.StatusConflict, `{"code":"MEMBERSHIP_LIMIT_REACHED","message":"You have already booked the maximum number of classes"}`, ErrNotBookable},
+		{"conflict without a known reason", http.StatusConflict, `{"message":"Request conflicts with the current state"}`, nil},
+		{"capacity of membership is not a full class", http.StatusBadRequest, `{"title":"Your membership has reached its capacity"}`, ErrNotBookable},
+		{"full by message", http.StatusBadRequest, `{"detail":"Sorry, this session is full"}`, ErrClassFull},
+		{"already booked by message", http.StatusBadRequest, `{"error":"Class already booked"}`, ErrAlreadyReserved},
+		{"booking window", http.StatusBadRequest, `{"code":"BOOKING_WINDOW_NOT_OPEN","message":"Reservations open 14 days in advance"}`, ErrOutsideBookingWindow},
+		{"pricing option", http.StatusBadRequest, `{"code":400,"message":"No pricing option available for this class"}`, ErrNotBookable},
+		{"unauthorized", http.StatusUnauthorized, `{"message":"Unauthorized"}`, ErrUnauthorized},
+		{"forbidden", http.StatusForbidden, `Forbidden`, ErrUnauthorized},
+		{"plain text", http.StatusBadGateway, `<html>Bad Gateway</html>`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newAPIError(tt.status, []byte(tt.body))
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", err, sentinel, got, !got)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	tests := map[string]string{
+		"CLASS_FULL":       "CLASS_FULL",
+		"ClassFull":        "CLASS_FULL",
+		"class-full":       "CLASS_FULL",
+		"already reserved": "ALREADY_RESERVED",
+		"":                 "",
+	}
+	for code, want := range tests {
+		if got := normalizeCode(code); got != want {
+			t.Errorf("normalizeCode(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
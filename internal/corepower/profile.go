@@ -0,0 +1,429 @@
+package corepower
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/geo"
+	"github.com/eshaanm25/corepower/internal/ical"
+	"github.com/eshaanm25/corepower/internal/opensearch"
+)
+
+// TimeOfDay is a wall clock time in minutes after midnight
+type TimeOfDay int
+
+// At returns the TimeOfDay for the given hour and minute
+func At(hour, minute int) TimeOfDay {
+	return TimeOfDay(hour*60 + minute)
+}
+
+// TimeOfDayOf returns the wall clock time of t in its own location
+func TimeOfDayOf(t time.Time) TimeOfDay {
+	return At(t.Hour(), t.Minute())
+}
+
+// On returns the time on the given day, in the day's location
+func (t TimeOfDay) On(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(t)/60, int(t)%60, 0, 0, day.Location())
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", int(t)/60, int(t)%60)
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *TimeOfDay) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	*t = TimeOfDayOf(parsed)
+	return nil
+}
+
+// Weekdays is a set of days, written in JSON as names like "mon" or "Monday"
+type Weekdays []time.Weekday
+
+// Contains reports whether day is in the set
+func (w Weekdays) Contains(day time.Weekday) bool {
+	for _, d := range w {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (w Weekdays) MarshalJSON() ([]byte, error) {
+	names := make([]string, 0, len(w))
+	for _, day := range w {
+		names = append(names, day.String())
+	}
+	return json.Marshal(names)
+}
+
+func (w *Weekdays) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+
+	*w = nil
+	for _, name := range names {
+		day, ok := ParseWeekday(name)
+		if !ok {
+			return fmt.Errorf("invalid weekday %q", name)
+		}
+		*w = append(*w, day)
+	}
+	return nil
+}
+
+// ParseWeekday parses a weekday name, or an abbreviation of at least three
+// letters, ignoring case
+func ParseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(name)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || (len(name) >= 3 && strings.HasPrefix(full, name)) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+type ClassPreference struct {
+	CenterName string    `json:"center,omitempty"` // Empty matches any studio
+	StartTime  TimeOfDay `json:"start"`
+	EndTime    TimeOfDay `json:"end"`
+	Preference int       `json:"preference"` // Lower number means higher preference
+}
+
+// Anchor is a place classes are travelled to from, such as home or the office
+type Anchor struct {
+	Name string `json:"name"`
+	geo.Point
+	Days     Weekdays `json:"days,omitempty"`      // Days the anchor applies, empty means every day
+	MaxMiles float64  `json:"max_miles,omitempty"` // Studios further away are skipped, 0 means no limit
+}
+
+// AppliesOn reports whether the anchor is used on the given weekday
+func (a Anchor) AppliesOn(day time.Weekday) bool {
+	return len(a.Days) == 0 || a.Days.Contains(day)
+}
+
+// Horizon limits how many days ahead classes are booked
+type Horizon struct {
+	MinDays int `json:"min_days"` // Classes starting sooner than this are skipped
+	MaxDays int `json:"max_days"` // Classes starting later than this are skipped
+
+	// NewestDayOnly only considers the day that was released last, the final
+	// 24 hours before MaxDays. It is the day that fills up fastest.
+	NewestDayOnly bool `json:"newest_day_only"`
+}
+
+// DefaultHorizon books only the newest day of CorePower's 14 day window
+var DefaultHorizon = Horizon{MaxDays: 14, NewestDayOnly: true}
+
+// Validate rejects horizons that give an empty or inverted window
+func (h Horizon) Validate() error {
+	if h.MinDays < 0 || h.MaxDays < 0 {
+		return fmt.Errorf("horizon min_days and max_days must not be negative")
+	}
+	if h.MaxDays == 0 {
+		return fmt.Errorf("horizon max_days must be at least 1")
+	}
+	if h.MinDays > h.MaxDays {
+		return fmt.Errorf("horizon min_days %d is after max_days %d", h.MinDays, h.MaxDays)
+	}
+	return nil
+}
+
+// Window returns the start times [from, to) classes are considered in
+func (h Horizon) Window(now time.Time) (from, to time.Time) {
+	to = now.AddDate(0, 0, h.MaxDays)
+	if h.NewestDayOnly {
+		return to.AddDate(0, 0, -1), to
+	}
+	return now.AddDate(0, 0, h.MinDays), to
+}
+
+// Contains reports whether a class starting at start is within the horizon
+func (h Horizon) Contains(now, start time.Time) bool {
+	from, to := h.Window(now)
+	return !start.Before(from) && start.Before(to)
+}
+
+// Profile describes which classes a user wants and where and when they want
+// them
+type Profile struct {
+	Category string `json:"category"`
+
+	// Timezone the preference windows are written in, e.g. "America/Denver".
+	// When empty, windows are read in each studio's own local time.
+	Timezone string         `json:"timezone,omitempty"`
+	Location *time.Location `json:"-"` // Loaded from Timezone by LoadLocation
+
+	WeekendDays Weekdays          `json:"weekend_days"` // Days that use the Weekend preferences
+	Weekend     []ClassPreference `json:"weekend"`
+	Weekday     []ClassPreference `json:"weekday"`
+
+	// Anchors restrict studios by distance. When any apply on a class's day,
+	// MilePenalty preference points are added per mile to the nearest one.
+	Anchors     []Anchor `json:"anchors,omitempty"`
+	MilePenalty float64  `json:"mile_penalty"`
+
+	Horizon Horizon `json:"horizon"`
+
+	// Calendars are ICS files or directories of them. Classes overlapping a
+	// busy event, widened by TravelBufferMinutes on both sides, are skipped.
+	Calendars           []string      `json:"calendars,omitempty"`
+	TravelBufferMinutes int           `json:"travel_buffer_minutes,omitempty"`
+	Busy                []ical.Period `json:"-"` // Loaded from Calendars by LoadCalendars
+}
+
+// NewProfile returns an empty profile with default settings, for user
+// configured preferences to be added to
+func NewProfile() Profile {
+	return Profile{
+		Category:    "Yoga Sculpt",
+		MilePenalty: 0.1,
+		Horizon:     DefaultHorizon,
+	}
+}
+
+// DefaultProfile returns the original Austin Yoga Sculpt preferences. Call
+// LoadLocation to filter the windows in Central Time on the search server.
+func DefaultProfile() Profile {
+	return Profile{
+		Category:    "Yoga Sculpt",
+		Timezone:    "America/Chicago",
+		WeekendDays: Weekdays{time.Saturday, time.Sunday, time.Monday, time.Friday},
+		MilePenalty: 0.1,
+		Horizon:     DefaultHorizon,
+		Weekend: []ClassPreference{
+			{CenterName: "Monarch", StartTime: At(13, 0), EndTime: At(15, 0), Preference: 1},    // 1:00 PM - 3:00 PM CT
+			{CenterName: "Mueller", StartTime: At(13, 0), EndTime: At(15, 0), Preference: 2},    // 1:00 PM - 3:00 PM CT
+			{CenterName: "Triangle", StartTime: At(11, 0), EndTime: At(15, 0), Preference: 2},   // 11:00 AM - 3:00 PM CT
+			{CenterName: "Cedar Park", StartTime: At(12, 0), EndTime: At(17, 0), Preference: 3}, // 12:00 PM - 5:00 PM CT
+		},
+		Weekday: []ClassPreference{
+			{CenterName: "Triangle", StartTime: At(17, 30), EndTime: At(18, 30), Preference: 1},   // 5:30 PM - 6:30 PM CT
+			{CenterName: "Mueller", StartTime: At(18, 0), EndTime: At(18, 45), Preference: 2},     // 6:00 PM - 6:45 PM CT
+			{CenterName: "Monarch", StartTime: At(18, 0), EndTime: At(18, 45), Preference: 2},     // 6:00 PM - 6:45 PM CT
+			{CenterName: "Cedar Park", StartTime: At(18, 30), EndTime: At(19, 30), Preference: 3}, // 6:30 PM - 7:30 PM CT
+		},
+	}
+}
+
+// LoadLocation resolves Timezone into Location
+func (p *Profile) LoadLocation() error {
+	if p.Timezone == "" {
+		p.Location = nil
+		return nil
+	}
+
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return fmt.Errorf("error loading timezone %q: %v", p.Timezone, err)
+	}
+	p.Location = location
+	return nil
+}
+
+// LoadCalendars reads the profile's calendars into Busy for the period between
+// from and to
+func (p *Profile) LoadCalendars(from, to time.Time) error {
+	p.Busy = nil
+	if len(p.Calendars) == 0 {
+		return nil
+	}
+
+	// Floating event times are read in the profile's time zone
+	location := p.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	calendar, err := ical.Load(location, p.Calendars...)
+	if err != nil {
+		return err
+	}
+	p.Busy = calendar.Busy(from, to)
+	return nil
+}
+
+// Conflict returns the busy period a class between start and end collides
+// with, counting the travel buffer
+func (p Profile) Conflict(start, end time.Time) (ical.Period, bool) {
+	buffer := time.Duration(p.TravelBufferMinutes) * time.Minute
+	for _, period := range p.Busy {
+		if period.Overlaps(start.Add(-buffer), end.Add(buffer)) {
+			return period, true
+		}
+	}
+	return ical.Period{}, false
+}
+
+// LocalTime returns the start of a class in the time zone its preferences are
+// evaluated in: the profile's, or else the studio's. The search engine's
+// start_time holds the studio's wall clock, so its difference from
+// start_time_utc is the offset in effect on that day, DST included.
+func (p Profile) LocalTime(startTime, startTimeUtc time.Time) time.Time {
+	if p.Location != nil {
+		return startTimeUtc.In(p.Location)
+	}
+	return StudioTime(startTime, startTimeUtc)
+}
+
+// StudioTime returns the start of a class in the studio's time zone, as a
+// fixed offset derived from the search engine's start_time and
+// start_time_utc. It is startTimeUtc when the two disagree.
+func StudioTime(startTime, startTimeUtc time.Time) time.Time {
+	if startTime.IsZero() {
+		return startTimeUtc
+	}
+
+	wall := time.Date(startTime.Year(), startTime.Month(), startTime.Day(),
+		startTime.Hour(), startTime.Minute(), startTime.Second(), 0, time.UTC)
+	offset := wall.Sub(startTimeUtc)
+	if offset%(15*time.Minute) != 0 || offset.Abs() > 14*time.Hour {
+		// Not a plausible time zone offset, the fields disagree
+		return startTimeUtc
+	}
+	return startTimeUtc.In(time.FixedZone(zoneName(offset), int(offset.Seconds())))
+}
+
+// zoneName formats an offset like "UTC-05:00"
+func zoneName(offset time.Duration) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	return fmt.Sprintf("UTC%s%02d:%02d", sign, int(offset.Hours()), int(offset.Minutes())%60)
+}
+
+// PreferencesFor returns the preferences that apply on the given weekday
+func (p Profile) PreferencesFor(weekday time.Weekday) []ClassPreference {
+	if p.WeekendDays.Contains(weekday) {
+		return p.Weekend
+	}
+	return p.Weekday
+}
+
+// Distance returns the distance in miles from the nearest anchor that applies
+// on the given weekday and allows the studio. ok is false when anchors apply
+// but the studio is too far from all of them. With no applicable anchors the
+// distance is 0.
+func (p Profile) Distance(weekday time.Weekday, studio geo.Point) (miles float64, ok bool) {
+	applies := false
+	for _, anchor := range p.Anchors {
+		if !anchor.AppliesOn(weekday) {
+			continue
+		}
+		applies = true
+
+		distance := geo.DistanceMiles(anchor.Point, studio)
+		if anchor.MaxMiles > 0 && distance > anchor.MaxMiles {
+			continue
+		}
+		if !ok || distance < miles {
+			miles, ok = distance, true
+		}
+	}
+	if !applies {
+		return 0, true
+	}
+	return miles, ok
+}
+
+// Query builds a search that only returns candidate classes for the profile:
+// bookable classes of the right category that start inside one of the
+// preference windows between from and to. Windows can only be filtered on when
+// the profile has a timezone; otherwise each studio's zone is only known from
+// the results. The client still checks every result, the filters just keep the
+// response small.
+func (p Profile) Query(from, to time.Time, centerIds []string) *opensearch.Query {
+	query := p.bookableQuery(centerIds)
+	if p.Location == nil {
+		return query.WhereAny(opensearch.StartingBetween(from, to))
+	}
+
+	// One start time window per day covering all of that day's preferences
+	var windows []opensearch.Filter
+	from, to = from.In(p.Location), to.In(p.Location)
+	firstDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, p.Location)
+	for day := firstDay; day.Before(to); day = day.AddDate(0, 0, 1) {
+		preferences := p.PreferencesFor(day.Weekday())
+		if len(preferences) == 0 {
+			continue
+		}
+
+		earliest, latest := preferences[0].StartTime, preferences[0].EndTime
+		for _, pref := range preferences[1:] {
+			earliest = min(earliest, pref.StartTime)
+			latest = max(latest, pref.EndTime)
+		}
+
+		// Windows include their end minute, ranges exclude their upper bound
+		start, end := earliest.On(day), latest.On(day).Add(time.Minute)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if start.Before(end) {
+			windows = append(windows, opensearch.StartingBetween(start, end))
+		}
+	}
+	if len(windows) == 0 {
+		// Nothing can match, but an empty "any" would match everything
+		windows = append(windows, opensearch.StartingBetween(from, from))
+	}
+	query.WhereAny(windows...)
+
+	return query
+}
+
+// HorizonQuery builds a search for every bookable class of the profile's
+// category starting between from and to, whatever the preferences
+func (p Profile) HorizonQuery(from, to time.Time, centerIds []string) *opensearch.Query {
+	return p.bookableQuery(centerIds).WhereAny(opensearch.StartingBetween(from, to))
+}
+
+// bookableQuery builds the filters and fields shared by the profile's searches
+func (p Profile) bookableQuery(centerIds []string) *opensearch.Query {
+	return opensearch.NewQuery().
+		Where(
+			opensearch.Centers(centerIds...),
+			opensearch.Categories(p.Category),
+			opensearch.CanBook(true),
+			opensearch.Statuses(2),
+			opensearch.Range(opensearch.FieldAvailableSlots, 1, nil),
+		).
+		SortBy(opensearch.FieldStartTime, "asc").
+		Fields(
+			opensearch.FieldAvailableSlots,
+			opensearch.FieldCanBook,
+			opensearch.FieldCenterID,
+			opensearch.FieldCenterName,
+			opensearch.FieldCenterLat,
+			opensearch.FieldCenterLong,
+			opensearch.FieldCategoryName,
+			opensearch.FieldSessionID,
+			opensearch.FieldStartTime,
+			opensearch.FieldStartTimeUtc,
+			opensearch.FieldEndTimeUtc,
+			opensearch.FieldStatus,
+		)
+}
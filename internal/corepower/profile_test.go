@@ -0,0 +1,111 @@
+package corepower
+
+import (
+	"testing"
+	"time"
+)
+
+// 2026 DST transitions in America/Chicago: clocks spring forward from 2:00
+// CST to 3:00 CDT on March 8 and fall back from 2:00 CDT to 1:00 CST on
+// November 1
+
+func utc(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func chicago(t *testing.T) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	return location
+}
+
+func TestLocalTimeInProfileZone(t *testing.T) {
+	profile := NewProfile()
+	profile.Location = chicago(t)
+
+	tests := []struct {
+		name         string
+		startTimeUtc string
+		wantClock    string // Wall clock in Chicago
+		wantOffset   int    // Hours from UTC
+	}{
+		{"day before spring forward", "2026-03-07T23:30:00Z", "17:30", -6},
+		{"day after spring forward", "2026-03-09T22:30:00Z", "17:30", -5},
+		{"before the gap on spring forward", "2026-03-08T07:30:00Z", "01:30", -6},
+		{"after the gap on spring forward", "2026-03-08T08:30:00Z", "03:30", -5},
+		{"day before fall back", "2026-10-31T22:30:00Z", "17:30", -5},
+		{"day after fall back", "2026-11-02T23:30:00Z", "17:30", -6},
+		{"first 1:30 on fall back", "2026-11-01T06:30:00Z", "01:30", -5},
+		{"second 1:30 on fall back", "2026-11-01T07:30:00Z", "01:30", -6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The search engine's wall clock is ignored when a zone is set
+			got := profile.LocalTime(time.Time{}, utc(tt.startTimeUtc))
+			if clock := got.Format("15:04"); clock != tt.wantClock {
+				t.Errorf("LocalTime = %s, want %s", clock, tt.wantClock)
+			}
+			if _, offset := got.Zone(); offset != tt.wantOffset*3600 {
+				t.Errorf("offset = %ds, want %dh", offset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestLocalTimeDerivedFromStudioClock(t *testing.T) {
+	// Without a profile zone the offset comes from the difference between the
+	// studio's wall clock, as the search engine returns it, and UTC
+	profile := NewProfile()
+
+	tests := []struct {
+		name         string
+		startTime    string // Studio wall clock, tagged as UTC by the search engine
+		startTimeUtc string
+		wantClock    string
+		wantOffset   time.Duration
+	}{
+		{"day before spring forward", "2026-03-07T17:30:00Z", "2026-03-07T23:30:00Z", "17:30", -6 * time.Hour},
+		{"day after spring forward", "2026-03-09T17:30:00Z", "2026-03-09T22:30:00Z", "17:30", -5 * time.Hour},
+		{"across the spring forward gap", "2026-03-08T03:30:00Z", "2026-03-08T08:30:00Z", "03:30", -5 * time.Hour},
+		{"day before fall back", "2026-10-31T17:30:00Z", "2026-10-31T22:30:00Z", "17:30", -5 * time.Hour},
+		{"day after fall back", "2026-11-02T17:30:00Z", "2026-11-02T23:30:00Z", "17:30", -6 * time.Hour},
+		{"second 1:30 on fall back", "2026-11-01T01:30:00Z", "2026-11-01T07:30:00Z", "01:30", -6 * time.Hour},
+		{"half hour zone", "2026-03-09T17:30:00Z", "2026-03-09T12:00:00Z", "17:30", 5*time.Hour + 30*time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := profile.LocalTime(utc(tt.startTime), utc(tt.startTimeUtc))
+			if clock := got.Format("15:04"); clock != tt.wantClock {
+				t.Errorf("LocalTime = %s, want %s", clock, tt.wantClock)
+			}
+			if _, offset := got.Zone(); time.Duration(offset)*time.Second != tt.wantOffset {
+				t.Errorf("offset = %ds, want %v", offset, tt.wantOffset)
+			}
+			if !got.Equal(utc(tt.startTimeUtc)) {
+				t.Errorf("LocalTime = %v, want the same instant as %s", got, tt.startTimeUtc)
+			}
+		})
+	}
+}
+
+func TestLocalTimeImplausibleOffset(t *testing.T) {
+	profile := NewProfile()
+	for _, startTime := range []string{"2026-03-09T17:37:00Z", "2026-03-11T17:30:00Z"} {
+		startTimeUtc := utc("2026-03-09T22:30:00Z")
+		if got := profile.LocalTime(utc(startTime), startTimeUtc); got != startTimeUtc {
+			t.Errorf("LocalTime(%s) = %v, want the UTC time unchanged", startTime, got)
+		}
+	}
+	if got := profile.LocalTime(time.Time{}, utc("2026-03-09T22:30:00Z")); got.Location() != time.UTC {
+		t.Errorf("LocalTime without a wall clock = %v, want UTC", got)
+	}
+}
@@ -0,0 +1,31 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceMiles(t *testing.T) {
+	austin := Point{Latitude: 30.2672, Longitude: -97.7431}
+	dallas := Point{Latitude: 32.7767, Longitude: -96.7970}
+	tests := []struct {
+		name string
+		a, b Point
+		want float64
+	}{
+		{"same point", austin, austin, 0},
+		{"austin to dallas", austin, dallas, 182.2},
+		{"one degree of latitude", Point{0, 0}, Point{1, 0}, 69.1},
+		{"across the antimeridian", Point{0, 179.5}, Point{0, -179.5}, 69.1},
+		{"antipodes", Point{0, 0}, Point{0, 180}, math.Pi * earthRadiusMiles},
+	}
+	for _, test := range tests {
+		got := DistanceMiles(test.a, test.b)
+		if math.Abs(got-test.want) > 0.1 {
+			t.Errorf("%s: got %.2f miles, want %.1f", test.name, got, test.want)
+		}
+		if back := DistanceMiles(test.b, test.a); math.Abs(back-got) > 1e-9 {
+			t.Errorf("%s: distance back is %.2f, not %.2f", test.name, back, got)
+		}
+	}
+}
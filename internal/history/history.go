@@ -0,0 +1,168 @@
+package history
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/corepower"
+)
+
+// Outcome is how a booking run ended
+type Outcome string
+
+const (
+	Booked          Outcome = "booked"
+	Waitlisted      Outcome = "waitlisted"
+	AlreadyReserved Outcome = "already_reserved"
+	NoMatch         Outcome = "no_match"
+	Failed          Outcome = "failed"
+)
+
+// Run is the record of one booking run
+type Run struct {
+	ID          string                         `json:"id"`
+	Time        time.Time                      `json:"time"`
+	User        string                         `json:"user,omitempty"`
+	Outcome     Outcome                        `json:"outcome"`
+	Search      *Search                        `json:"search,omitempty"`
+	Class       *corepower.Result              `json:"class,omitempty"` // The class booked, or the last one tried
+	Reservation *corepower.ReservationResponse `json:"reservation,omitempty"`
+	Attempts    []Attempt                      `json:"attempts,omitempty"`
+	Error       string                         `json:"error,omitempty"`
+}
+
+// Search summarizes the search a run made
+type Search struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Centers    []string  `json:"centers,omitempty"`
+	Results    int       `json:"results"`    // Classes returned by the search
+	Candidates int       `json:"candidates"` // Classes matching the preferences
+}
+
+// Attempt is one reservation request made during a run
+type Attempt struct {
+	CenterName string    `json:"center_name"`
+	ClassName  string    `json:"class_name"`
+	StartTime  time.Time `json:"start_time"`
+	SessionID  int64     `json:"session_id"`
+	Outcome    Outcome   `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// NewAttempt records a reservation request for a ranked class
+func NewAttempt(class corepower.Result, outcome Outcome, err error) Attempt {
+	attempt := Attempt{
+		CenterName: class.CenterName,
+		ClassName:  class.ClassCategoryName,
+		StartTime:  class.StartTime,
+		SessionID:  class.SessionID,
+		Outcome:    outcome,
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	return attempt
+}
+
+// Store keeps runs in a JSON lines file, one run per line, so that appending
+// never rewrites earlier records. Its lock only orders the goroutines sharing
+// it, so a process should open a file once and share the Store.
+type Store struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// DefaultPath is where runs are recorded unless configured otherwise
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding config directory: %v", err)
+	}
+	return filepath.Join(dir, "corepower", "history.jsonl"), nil
+}
+
+// Open returns the store at path, the file is created on the first Append
+func Open(path string) *Store {
+	return &Store{Path: path}
+}
+
+// Append records a run
+func (s *Store) Append(run Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slog.Debug("Recording run", "path", s.Path, "id", run.ID, "outcome", run.Outcome)
+	return appendLines(s.Path, run)
+}
+
+// Runs returns the recorded runs matching the filter, oldest first. A missing
+// file has no runs.
+func (s *Store) Runs(filter Filter) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var runs []Run
+	err := readLines(s.Path, func(run Run) {
+		if filter.Matches(run) {
+			runs = append(runs, run)
+		}
+	})
+	return runs, err
+}
+
+// Filter selects runs. Zero fields match everything; Center and Class match
+// case-insensitive substrings of the chosen class.
+type Filter struct {
+	Since    time.Time
+	Until    time.Time
+	User     string
+	Outcomes []Outcome
+	Center   string
+	Class    string
+	Weekdays []time.Weekday // Weekday of the chosen class
+}
+
+// Matches reports whether a run passes the filter
+func (f Filter) Matches(run Run) bool {
+	if !f.Since.IsZero() && run.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !run.Time.Before(f.Until) {
+		return false
+	}
+	if f.User != "" && !strings.EqualFold(run.User, f.User) {
+		return false
+	}
+	if len(f.Outcomes) > 0 && !slices.Contains(f.Outcomes, run.Outcome) {
+		return false
+	}
+
+	if f.Center == "" && f.Class == "" && len(f.Weekdays) == 0 {
+		return true
+	}
+	if run.Class == nil {
+		return false
+	}
+	if f.Center != "" && !strings.Contains(strings.ToLower(run.Class.CenterName), strings.ToLower(f.Center)) {
+		return false
+	}
+	if f.Class != "" && !strings.Contains(strings.ToLower(run.Class.ClassCategoryName), strings.ToLower(f.Class)) {
+		return false
+	}
+	if len(f.Weekdays) > 0 && !slices.Contains(f.Weekdays, run.Class.StartTime.Weekday()) {
+		return false
+	}
+	return true
+}
+
+// NewRunID returns an ID that sorts by the time the run started
+func NewRunID(now time.Time) string {
+	return fmt.Sprintf("%s-%04x", now.UTC().Format("20060102T150405Z"), rand.N(0x10000))
+}
@@ -0,0 +1,123 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// appendLines writes each value as a line of JSON at the end of the file,
+// creating it if needed. The lines go out in a single write so a concurrent
+// reader never sees half of a batch.
+func appendLines[T any](path string, values ...T) error {
+	var buf bytes.Buffer
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("error marshaling record: %v", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating history directory: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %v", path, err)
+	}
+	if err := dropPartialLine(f); err != nil {
+		f.Close()
+		return fmt.Errorf("error repairing %s: %v", path, err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return f.Close()
+}
+
+// dropPartialLine truncates a last line left without its newline by a
+// process killed while writing, so that new lines do not run on from it
+func dropPartialLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, size-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+
+	// Look backwards for the end of the last complete line
+	chunk := make([]byte, 64*1024)
+	end := size
+	for end > 0 {
+		start := max(end-int64(len(chunk)), 0)
+		n, err := f.ReadAt(chunk[:end-start], start)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if i := bytes.LastIndexByte(chunk[:n], '\n'); i >= 0 {
+			end = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+	slog.Warn("Dropping incomplete last line of history file", "path", f.Name(), "bytes", size-end)
+	return f.Truncate(end)
+}
+
+// readLines calls fn with each line of JSON in the file. A missing file has
+// no lines. An unreadable last line, left by a process killed while writing,
+// is skipped with a warning; unreadable lines before it are an error.
+func readLines[T any](path string, fn func(T)) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error opening %s: %v", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var parseErr error
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		// Only the last line may be partial, so a bad line followed by
+		// another is corruption
+		if parseErr != nil {
+			return parseErr
+		}
+		var value T
+		if err := json.Unmarshal(scanner.Bytes(), &value); err != nil {
+			parseErr = fmt.Errorf("error parsing %s line %d: %v", path, line, err)
+			continue
+		}
+		fn(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+	if parseErr != nil {
+		slog.Warn("Skipping incomplete last line of history file", "error", parseErr)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	N int `json:"n"`
+}
+
+func TestReadLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []int
+		wantErr string
+	}{
+		{"empty", "", nil, ""},
+		{"complete", "{\"n\":1}\n{\"n\":2}\n", []int{1, 2}, ""},
+		{"blank lines", "{\"n\":1}\n\n  \n{\"n\":2}\n", []int{1, 2}, ""},
+		{"partial last line", "{\"n\":1}\n{\"n\":2}\n{\"n\":", []int{1, 2}, ""},
+		{"bad last line then blanks", "{\"n\":1}\n{\"n\n\n", []int{1}, ""},
+		{"corrupt middle line", "{\"n\":1}\ngarbage\n{\"n\":3}\n", nil, "line 2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "history.jsonl")
+			if err := os.WriteFile(path, []byte(test.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int
+			err := readLines(path, func(r record) { got = append(got, r.N) })
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("readLines = %v, want an error mentioning %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readLines: %v", err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("read %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadLinesMissingFile(t *testing.T) {
+	err := readLines(filepath.Join(t.TempDir(), "missing.jsonl"), func(record) { t.Error("called for a missing file") })
+	if err != nil {
+		t.Errorf("readLines = %v, want nil", err)
+	}
+}
+
+func TestAppendAfterPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n{\"n\":"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendLines(path, record{2}, record{3}); err != nil {
+		t.Fatalf("appendLines: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"; string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,200 @@
+package history
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/corepower"
+)
+
+// PredictConfig tunes how much recorded fill history changes the ranking
+type PredictConfig struct {
+	// Score added for a class that is certain not to be attended, scaled by
+	// the chance of that. 1 is worth one preference rank, 0 turns it off.
+	Weight float64 `json:"weight"`
+
+	// Score added for a class whose instructor is always substituted, 0
+	// turns it off
+	SubstitutionWeight float64 `json:"substitution_weight"`
+
+	// Fewest similar recorded sessions a prediction is based on, classes
+	// with less history are not adjusted
+	MinSessions int `json:"min_sessions,omitempty"`
+
+	// File snapshots are read from, defaults to the collect command's file
+	Snapshots string `json:"snapshots,omitempty"`
+}
+
+// DefaultPredictConfig is used for settings the config leaves out
+var DefaultPredictConfig = PredictConfig{
+	Weight:             1,
+	SubstitutionWeight: 0.5,
+	MinSessions:        5,
+}
+
+// UnmarshalJSON fills in defaults for the settings the JSON leaves out, so
+// that an explicit 0 weight is kept
+func (c *PredictConfig) UnmarshalJSON(data []byte) error {
+	type plain PredictConfig
+	config := plain(DefaultPredictConfig)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	*c = PredictConfig(config)
+	return nil
+}
+
+// Prediction estimates how a class will go from similar recorded sessions
+type Prediction struct {
+	Sessions     int     `json:"sessions"`     // Similar sessions the estimate is based on
+	Booking      float64 `json:"booking"`      // Chance a spot is still free when booking now
+	Waitlist     float64 `json:"waitlist"`     // Chance a full class frees up a spot later
+	Cancellation float64 `json:"cancellation"` // Chance the studio cancels the class
+	Substitution float64 `json:"substitution"` // Chance the instructor is substituted
+}
+
+// Attendance is the chance of getting into the class and it taking place,
+// either by booking or through the waitlist
+func (p Prediction) Attendance() float64 {
+	return (p.Booking + (1-p.Booking)*p.Waitlist) * (1 - p.Cancellation)
+}
+
+// Predictor estimates the outcome of booking a class from the fill curves of
+// sessions at the same studio and time. It is a corepower.Scorer.
+type Predictor struct {
+	Config      PredictConfig
+	fills       []Fill
+	cancelled   map[string]bool
+	reopened    map[string]bool
+	substituted map[string]bool
+}
+
+// NewPredictor learns from recorded snapshots, as returned by Series. Zero
+// weights are kept, a zero MinSessions takes the default.
+func NewPredictor(series map[string][]Snapshot, config PredictConfig) *Predictor {
+	if config.MinSessions <= 0 {
+		config.MinSessions = DefaultPredictConfig.MinSessions
+	}
+
+	// Every time each studio was collected, to tell a session that
+	// disappeared from one that was no longer searched for
+	collections := map[string][]time.Time{}
+	for _, snapshots := range series {
+		for _, snapshot := range snapshots {
+			collections[snapshot.CenterID] = append(collections[snapshot.CenterID], snapshot.Time)
+		}
+	}
+	for center, times := range collections {
+		slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+		collections[center] = slices.Compact(times)
+	}
+
+	p := &Predictor{
+		Config:      config,
+		fills:       Fills(series),
+		cancelled:   map[string]bool{},
+		reopened:    map[string]bool{},
+		substituted: map[string]bool{},
+	}
+	for guid, snapshots := range series {
+		last := snapshots[len(snapshots)-1]
+
+		// A session that stopped showing up while classes were still being
+		// collected, before it started, was cancelled
+		times := collections[last.CenterID]
+		i, _ := slices.BinarySearchFunc(times, last.Time, func(a, b time.Time) int { return a.Compare(b) })
+		for _, collected := range times[i:] {
+			if collected.After(last.Time) && collected.Before(last.StartTimeUtc) {
+				p.cancelled[guid] = true
+				break
+			}
+		}
+
+		full := false
+		for _, snapshot := range snapshots {
+			if snapshot.Full() {
+				full = true
+			} else if full {
+				p.reopened[guid] = true
+			}
+			if snapshot.Substituted {
+				p.substituted[guid] = true
+			}
+		}
+	}
+	return p
+}
+
+// Predict estimates how booking the class now would go. Sessions at the same
+// studio on the same weekday and time are used, or at the same studio and
+// time on any day when there are too few of those. Weekday and time are on
+// the studio's clock, whatever zone the preferences are evaluated in.
+func (p *Predictor) Predict(class corepower.Result, now time.Time) Prediction {
+	start := class.StudioTime
+	if start.IsZero() {
+		start = class.StartTime
+	}
+	slot := start.Format("15:04")
+	var sameDay, anyDay []Fill
+	for _, fill := range p.fills {
+		fillStart := corepower.StudioTime(fill.Latest.StartTime, fill.Latest.StartTimeUtc)
+		if fill.Latest.CenterID != class.CenterID || fillStart.Format("15:04") != slot {
+			continue
+		}
+		anyDay = append(anyDay, fill)
+		if fillStart.Weekday() == start.Weekday() {
+			sameDay = append(sameDay, fill)
+		}
+	}
+	similar := sameDay
+	if len(similar) < p.Config.MinSessions {
+		similar = anyDay
+	}
+
+	prediction := Prediction{Sessions: len(similar)}
+	if len(similar) == 0 {
+		return prediction
+	}
+
+	lead := class.StartTimeUtc.Sub(now)
+	var free, filled, reopened, cancelled, substituted int
+	for _, fill := range similar {
+		guid := fill.Latest.SessionGUID
+		// Still free this long before the start
+		if leadTime, ok := fill.LeadTime(); !ok || leadTime < lead {
+			free++
+		}
+		if fill.Full() {
+			filled++
+			if p.reopened[guid] {
+				reopened++
+			}
+		}
+		if p.cancelled[guid] {
+			cancelled++
+		}
+		if p.substituted[guid] {
+			substituted++
+		}
+	}
+
+	n := float64(len(similar))
+	prediction.Booking = float64(free) / n
+	prediction.Cancellation = float64(cancelled) / n
+	prediction.Substitution = float64(substituted) / n
+	if filled > 0 {
+		prediction.Waitlist = float64(reopened) / float64(filled)
+	}
+	return prediction
+}
+
+// Adjust penalizes classes that are unlikely to be attended or likely to have
+// a substitute instructor. Classes with too little history are left alone.
+func (p *Predictor) Adjust(class corepower.Result, now time.Time) float64 {
+	prediction := p.Predict(class, now)
+	if prediction.Sessions < p.Config.MinSessions {
+		return 0
+	}
+	return p.Config.Weight*(1-prediction.Attendance()) + p.Config.SubstitutionWeight*prediction.Substitution
+}
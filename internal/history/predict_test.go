@@ -0,0 +1,217 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/corepower"
+)
+
+// studioOffset is the studio's UTC offset in the tests, one hour behind the
+// profile zone so that the two clocks never agree
+const studioOffset = -7 * time.Hour
+
+var profileZone = time.FixedZone("profile", -6*3600)
+
+// session describes a recorded session for building snapshot series
+type session struct {
+	center      string
+	wall        string        // Studio wall clock, e.g. "2026-03-02 18:00"
+	fullBefore  time.Duration // How long before the start it filled, 0 if never
+	substituted bool
+}
+
+// series builds the snapshots of sessions: free three days ahead, full from
+// fullBefore and a last look an hour before the start
+func series(t *testing.T, sessions ...session) map[string][]Snapshot {
+	t.Helper()
+	result := map[string][]Snapshot{}
+	for i, s := range sessions {
+		wall, err := time.Parse("2006-01-02 15:04", s.wall)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := wall.Add(-studioOffset)
+		snapshot := func(at time.Duration, full bool) Snapshot {
+			snap := Snapshot{
+				Time:           start.Add(-at),
+				SessionGUID:    fmt.Sprintf("session-%d", i),
+				CenterID:       s.center,
+				StartTime:      wall, // Wall clock labelled UTC, as the search returns it
+				StartTimeUtc:   start,
+				Capacity:       30,
+				AvailableSlots: 10,
+				Substituted:    s.substituted,
+			}
+			if full {
+				snap.AvailableSlots = 0
+			}
+			return snap
+		}
+
+		guid := fmt.Sprintf("session-%d", i)
+		result[guid] = append(result[guid], snapshot(72*time.Hour, false))
+		if s.fullBefore > 0 {
+			result[guid] = append(result[guid], snapshot(s.fullBefore, true))
+		}
+		result[guid] = append(result[guid], snapshot(time.Hour, s.fullBefore >= time.Hour))
+	}
+	return result
+}
+
+// class is a search result at the test studio, with its start in the
+// profile zone the way NewResult converts it
+func class(t *testing.T, center, wall string) corepower.Result {
+	t.Helper()
+	parsed, err := time.Parse("2006-01-02 15:04", wall)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := parsed.Add(-studioOffset)
+	return corepower.Result{
+		CenterID:     center,
+		StartTime:    utc.In(profileZone),
+		StudioTime:   corepower.StudioTime(parsed, utc),
+		StartTimeUtc: utc,
+	}
+}
+
+func TestPredict(t *testing.T) {
+	mondays := []session{
+		{center: "d1", wall: "2026-02-02 18:00", fullBefore: 48 * time.Hour},
+		{center: "d1", wall: "2026-02-09 18:00", fullBefore: 48 * time.Hour},
+		{center: "d1", wall: "2026-02-16 18:00"},
+		{center: "d1", wall: "2026-02-23 18:00"},
+	}
+	tuesdays := []session{
+		{center: "d1", wall: "2026-02-03 18:00"},
+		{center: "d1", wall: "2026-02-10 18:00"},
+	}
+	elsewhere := []session{
+		{center: "d1", wall: "2026-02-02 09:30", fullBefore: 60 * time.Hour},
+		{center: "a1", wall: "2026-02-02 18:00", fullBefore: 60 * time.Hour},
+	}
+	all := func(groups ...[]session) []session {
+		var sessions []session
+		for _, group := range groups {
+			sessions = append(sessions, group...)
+		}
+		return sessions
+	}
+
+	tests := []struct {
+		name        string
+		sessions    []session
+		minSessions int
+		class       string // Studio wall clock of the class
+		now         time.Duration
+		want        Prediction
+	}{
+		{
+			name:        "same weekday when there are enough",
+			sessions:    all(mondays, tuesdays, elsewhere),
+			minSessions: 4,
+			class:       "2026-03-02 18:00",
+			now:         24 * time.Hour,
+			want:        Prediction{Sessions: 4, Booking: 0.5},
+		},
+		{
+			name:        "any weekday when too few on the same one",
+			sessions:    all(mondays, tuesdays, elsewhere),
+			minSessions: 5,
+			class:       "2026-03-02 18:00",
+			now:         24 * time.Hour,
+			want:        Prediction{Sessions: 6, Booking: 4.0 / 6},
+		},
+		{
+			name:        "booking early enough to find them all free",
+			sessions:    all(mondays),
+			minSessions: 4,
+			class:       "2026-03-02 18:00",
+			now:         60 * time.Hour,
+			want:        Prediction{Sessions: 4, Booking: 1},
+		},
+		{
+			name:        "no session at that time",
+			sessions:    all(mondays, tuesdays),
+			minSessions: 1,
+			class:       "2026-03-02 19:00",
+			now:         72 * time.Hour,
+			want:        Prediction{},
+		},
+		{
+			name:        "substitutions",
+			sessions:    []session{{center: "d1", wall: "2026-02-02 18:00", substituted: true}, {center: "d1", wall: "2026-02-09 18:00"}},
+			minSessions: 2,
+			class:       "2026-03-02 18:00",
+			now:         72 * time.Hour,
+			want:        Prediction{Sessions: 2, Booking: 1, Substitution: 0.5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			predictor := NewPredictor(series(t, test.sessions...), PredictConfig{Weight: 1, MinSessions: test.minSessions})
+			c := class(t, "d1", test.class)
+			got := predictor.Predict(c, c.StartTimeUtc.Add(-test.now))
+			if got.Sessions != test.want.Sessions || !near(got.Booking, test.want.Booking) ||
+				!near(got.Substitution, test.want.Substitution) || got.Cancellation != 0 {
+				t.Errorf("Predict = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	filledEarly := series(t,
+		session{center: "d1", wall: "2026-02-02 18:00", fullBefore: 70 * time.Hour, substituted: true},
+		session{center: "d1", wall: "2026-02-09 18:00", fullBefore: 70 * time.Hour, substituted: true},
+	)
+
+	tests := []struct {
+		name   string
+		config PredictConfig
+		want   float64
+	}{
+		{"full and substituted", PredictConfig{Weight: 1, SubstitutionWeight: 0.5, MinSessions: 2}, 1.5},
+		{"weights scale", PredictConfig{Weight: 2, SubstitutionWeight: 0.25, MinSessions: 2}, 2.25},
+		{"substitution off", PredictConfig{Weight: 1, MinSessions: 2}, 1},
+		{"prediction off", PredictConfig{MinSessions: 2}, 0},
+		{"too few sessions", PredictConfig{Weight: 1, SubstitutionWeight: 0.5, MinSessions: 3}, 0},
+	}
+	for _, test := range tests {
+		predictor := NewPredictor(filledEarly, test.config)
+		c := class(t, "d1", "2026-03-02 18:00")
+		if got := predictor.Adjust(c, c.StartTimeUtc.Add(-24*time.Hour)); !near(got, test.want) {
+			t.Errorf("%s: Adjust = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPredictConfigDefaults(t *testing.T) {
+	tests := []struct {
+		json string
+		want PredictConfig
+	}{
+		{`{}`, DefaultPredictConfig},
+		{`{"weight": 0}`, PredictConfig{Weight: 0, SubstitutionWeight: 0.5, MinSessions: 5}},
+		{`{"substitution_weight": 0, "min_sessions": 3}`, PredictConfig{Weight: 1, MinSessions: 3}},
+		{`{"weight": 2, "snapshots": "s.jsonl"}`, PredictConfig{Weight: 2, SubstitutionWeight: 0.5, MinSessions: 5, Snapshots: "s.jsonl"}},
+	}
+	for _, test := range tests {
+		var got PredictConfig
+		if err := json.Unmarshal([]byte(test.json), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.json, got, test.want)
+		}
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
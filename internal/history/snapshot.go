@@ -0,0 +1,155 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/opensearch"
+)
+
+// Snapshot is the availability of one class session at one point in time
+type Snapshot struct {
+	Time            time.Time `json:"time"`
+	SessionGUID     string    `json:"session_guid"`
+	SessionID       int64     `json:"session_id"`
+	CenterID        string    `json:"center_id"`
+	CenterName      string    `json:"center_name"`
+	ClassName       string    `json:"class_name"`
+	Instructor      string    `json:"instructor,omitempty"`
+	Substituted     bool      `json:"substituted,omitempty"`
+	StartTime       time.Time `json:"start_time"` // Studio wall clock, as returned by the search
+	StartTimeUtc    time.Time `json:"start_time_utc"`
+	AvailableSlots  float32   `json:"available_slots"`
+	Capacity        float32   `json:"capacity"`
+	Occupancy       float32   `json:"occupancy"`
+	WaitListedCount float32   `json:"wait_listed_count"`
+}
+
+// Full reports whether the session had no free spots
+func (s Snapshot) Full() bool {
+	return s.AvailableSlots <= 0 || (s.Capacity > 0 && s.Occupancy >= s.Capacity)
+}
+
+// Snapshots records the availability of every class in a search response
+func Snapshots(res *opensearch.SearchResponse, now time.Time) []Snapshot {
+	snapshots := make([]Snapshot, 0, len(res.Results))
+	for _, class := range res.Results {
+		if class.SessionGUID.Raw == "" {
+			continue
+		}
+		instructor := class.Instructors.Name.Raw
+		if instructor == "" {
+			instructor = class.InstructorID.Raw
+		}
+		snapshots = append(snapshots, Snapshot{
+			Time:            now,
+			SessionGUID:     class.SessionGUID.Raw,
+			SessionID:       class.SessionID.Raw,
+			CenterID:        class.CenterID.Raw,
+			CenterName:      class.CenterName.Raw,
+			ClassName:       class.ClassCategoryName.Raw,
+			Instructor:      instructor,
+			Substituted:     class.IsInstructorSubstituted.Raw == "true",
+			StartTime:       class.StartTime.Raw,
+			StartTimeUtc:    class.StartTimeUtc.Raw,
+			AvailableSlots:  class.AvailableSlots.Raw,
+			Capacity:        class.Capacity.Raw,
+			Occupancy:       class.Occupancy.Raw,
+			WaitListedCount: class.WaitListedCount.Raw,
+		})
+	}
+	return snapshots
+}
+
+// SnapshotQuery searches the given studios for every active class starting
+// between from and to, including full ones, with the fields snapshots need.
+// An empty category collects all of them.
+func SnapshotQuery(from, to time.Time, centerIds []string, category string) *opensearch.Query {
+	query := opensearch.NewQuery().
+		Where(
+			opensearch.Centers(centerIds...),
+			opensearch.Statuses(2),
+			opensearch.StartingBetween(from, to),
+		).
+		SortBy(opensearch.FieldStartTime, "asc").
+		Fields(
+			opensearch.FieldAvailableSlots,
+			opensearch.FieldCapacity,
+			opensearch.FieldCenterID,
+			opensearch.FieldCenterName,
+			opensearch.FieldCategoryName,
+			opensearch.FieldInstructorID,
+			opensearch.FieldInstructors,
+			opensearch.FieldIsInstructorSubstituted,
+			opensearch.FieldOccupancy,
+			opensearch.FieldSessionGUID,
+			opensearch.FieldSessionID,
+			opensearch.FieldStartTime,
+			opensearch.FieldStartTimeUtc,
+			opensearch.FieldWaitListedCount,
+		)
+	if category != "" {
+		query.Where(opensearch.Categories(category))
+	}
+	return query
+}
+
+// SnapshotStore keeps snapshots in a JSON lines file
+type SnapshotStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// DefaultSnapshotPath is where snapshots are recorded unless configured
+// otherwise, next to the run history
+func DefaultSnapshotPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding config directory: %v", err)
+	}
+	return filepath.Join(dir, "corepower", "snapshots.jsonl"), nil
+}
+
+// OpenSnapshots returns the snapshot store at path
+func OpenSnapshots(path string) *SnapshotStore {
+	return &SnapshotStore{Path: path}
+}
+
+// Append records snapshots taken together
+func (s *SnapshotStore) Append(snapshots []Snapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendLines(s.Path, snapshots...)
+}
+
+// Series returns the snapshots of sessions starting between from and to,
+// oldest first, keyed by session GUID. Zero times do not limit the range.
+func (s *SnapshotStore) Series(from, to time.Time) (map[string][]Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	series := map[string][]Snapshot{}
+	err := readLines(s.Path, func(snapshot Snapshot) {
+		if !from.IsZero() && snapshot.StartTimeUtc.Before(from) {
+			return
+		}
+		if !to.IsZero() && !snapshot.StartTimeUtc.Before(to) {
+			return
+		}
+		series[snapshot.SessionGUID] = append(series[snapshot.SessionGUID], snapshot)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshots := range series {
+		slices.SortStableFunc(snapshots, func(a, b Snapshot) int { return a.Time.Compare(b.Time) })
+	}
+	return series, nil
+}
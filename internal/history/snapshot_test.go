@@ -0,0 +1,71 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	store := OpenSnapshots(filepath.Join(t.TempDir(), "snapshots.jsonl"))
+	base := time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)
+	snap := func(guid string, takenHours, startHours int, slots float32) Snapshot {
+		return Snapshot{
+			Time:           base.Add(time.Duration(takenHours) * time.Hour),
+			SessionGUID:    guid,
+			CenterID:       "c1",
+			StartTimeUtc:   base.Add(time.Duration(startHours) * time.Hour),
+			AvailableSlots: slots,
+		}
+	}
+
+	// Written out of order across two collections
+	if err := store.Append([]Snapshot{snap("a", 5, 24, 2), snap("b", 5, 48, 9)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append([]Snapshot{snap("a", 1, 24, 8), snap("c", 1, 96, 9)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     map[string]int
+	}{
+		{"everything", time.Time{}, time.Time{}, map[string]int{"a": 2, "b": 1, "c": 1}},
+		{"from is inclusive", base.Add(48 * time.Hour), time.Time{}, map[string]int{"b": 1, "c": 1}},
+		{"to is exclusive", time.Time{}, base.Add(48 * time.Hour), map[string]int{"a": 2}},
+	}
+	for _, test := range tests {
+		series, err := store.Series(test.from, test.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(series) != len(test.want) {
+			t.Errorf("%s: %d sessions, want %d", test.name, len(series), len(test.want))
+		}
+		for guid, n := range test.want {
+			if len(series[guid]) != n {
+				t.Errorf("%s: session %s has %d snapshots, want %d", test.name, guid, len(series[guid]), n)
+			}
+		}
+	}
+
+	series, err := store.Series(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := series["a"]; a[0].AvailableSlots != 8 || a[1].AvailableSlots != 2 {
+		t.Errorf("session a is not oldest first: %+v", a)
+	}
+}
+
+func TestSnapshotStoreMissingFile(t *testing.T) {
+	series, err := OpenSnapshots(filepath.Join(t.TempDir(), "missing.jsonl")).Series(time.Time{}, time.Time{})
+	if err != nil || len(series) != 0 {
+		t.Errorf("Series = %v, %v, want no sessions", series, err)
+	}
+}
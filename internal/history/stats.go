@@ -0,0 +1,117 @@
+package history
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Fill is how a session's availability developed over its snapshots
+type Fill struct {
+	Latest    Snapshot  // Most recent snapshot, for the session's details
+	FirstSeen time.Time // First snapshot
+	FullAt    time.Time // First snapshot showing it full, zero if it never was
+}
+
+// Full reports whether the session was seen full
+func (f Fill) Full() bool {
+	return !f.FullAt.IsZero()
+}
+
+// TimeToFull is how long after it was first seen with free spots the session
+// was first seen full. Sessions already full when first seen have no time to
+// full, since when they filled is unknown.
+func (f Fill) TimeToFull() (time.Duration, bool) {
+	if !f.Full() || !f.FullAt.After(f.FirstSeen) {
+		return 0, false
+	}
+	return f.FullAt.Sub(f.FirstSeen), true
+}
+
+// LeadTime is how long before the class started it was first seen full
+func (f Fill) LeadTime() (time.Duration, bool) {
+	if !f.Full() {
+		return 0, false
+	}
+	return f.Latest.StartTimeUtc.Sub(f.FullAt), true
+}
+
+// Fills summarizes each session's snapshots, as returned by Series
+func Fills(series map[string][]Snapshot) []Fill {
+	fills := make([]Fill, 0, len(series))
+	for _, snapshots := range series {
+		if len(snapshots) == 0 {
+			continue
+		}
+		fill := Fill{Latest: snapshots[len(snapshots)-1], FirstSeen: snapshots[0].Time}
+		for _, snapshot := range snapshots {
+			if snapshot.Full() {
+				fill.FullAt = snapshot.Time
+				break
+			}
+		}
+		fills = append(fills, fill)
+	}
+	slices.SortFunc(fills, func(a, b Fill) int { return a.Latest.StartTimeUtc.Compare(b.Latest.StartTimeUtc) })
+	return fills
+}
+
+// FillStats summarizes the fills sharing a key, e.g. a studio or weekday
+type FillStats struct {
+	Key              string
+	Sessions         int
+	Filled           int
+	MedianTimeToFull time.Duration // Zero when no session has a time to full
+	MedianLeadTime   time.Duration // How long before the start sessions were full
+}
+
+// FillRate is the share of sessions that were seen full
+func (s FillStats) FillRate() float64 {
+	if s.Sessions == 0 {
+		return 0
+	}
+	return float64(s.Filled) / float64(s.Sessions)
+}
+
+// GroupFills summarizes fills by the key returned for each, sorted by key
+func GroupFills(fills []Fill, key func(Fill) string) []FillStats {
+	groups := map[string][]Fill{}
+	for _, fill := range fills {
+		k := key(fill)
+		groups[k] = append(groups[k], fill)
+	}
+
+	stats := make([]FillStats, 0, len(groups))
+	for k, group := range groups {
+		s := FillStats{Key: k, Sessions: len(group)}
+		var timesToFull, leadTimes []time.Duration
+		for _, fill := range group {
+			if fill.Full() {
+				s.Filled++
+			}
+			if d, ok := fill.TimeToFull(); ok {
+				timesToFull = append(timesToFull, d)
+			}
+			if d, ok := fill.LeadTime(); ok {
+				leadTimes = append(leadTimes, d)
+			}
+		}
+		s.MedianTimeToFull = median(timesToFull)
+		s.MedianLeadTime = median(leadTimes)
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b FillStats) int { return cmp.Compare(a.Key, b.Key) })
+	return stats
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	slices.Sort(durations)
+	mid := len(durations) / 2
+	if len(durations)%2 == 0 {
+		return (durations[mid-1] + durations[mid]) / 2
+	}
+	return durations[mid]
+}
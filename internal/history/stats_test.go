@@ -0,0 +1,92 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFills(t *testing.T) {
+	start := time.Date(2026, time.March, 2, 18, 0, 0, 0, time.UTC)
+	snap := func(guid string, hoursBefore int, slots float32) Snapshot {
+		return Snapshot{
+			Time:           start.Add(-time.Duration(hoursBefore) * time.Hour),
+			SessionGUID:    guid,
+			StartTimeUtc:   start,
+			Capacity:       30,
+			AvailableSlots: slots,
+		}
+	}
+	series := map[string][]Snapshot{
+		"filled":       {snap("filled", 72, 10), snap("filled", 48, 0), snap("filled", 24, 0)},
+		"never full":   {snap("never full", 72, 10), snap("never full", 1, 3)},
+		"full at once": {snap("full at once", 30, 0)},
+	}
+
+	tests := []struct {
+		guid       string
+		full       bool
+		timeToFull time.Duration
+		hasTime    bool
+		leadTime   time.Duration
+	}{
+		{"filled", true, 24 * time.Hour, true, 48 * time.Hour},
+		{"never full", false, 0, false, 0},
+		// When it filled before it was first seen is unknown
+		{"full at once", true, 0, false, 30 * time.Hour},
+	}
+
+	fills := map[string]Fill{}
+	for _, fill := range Fills(series) {
+		fills[fill.Latest.SessionGUID] = fill
+	}
+	for _, test := range tests {
+		fill := fills[test.guid]
+		if fill.Full() != test.full {
+			t.Errorf("%s: Full = %v, want %v", test.guid, fill.Full(), test.full)
+		}
+		if d, ok := fill.TimeToFull(); ok != test.hasTime || d != test.timeToFull {
+			t.Errorf("%s: TimeToFull = %v, %v, want %v, %v", test.guid, d, ok, test.timeToFull, test.hasTime)
+		}
+		if d, ok := fill.LeadTime(); ok != test.full || d != test.leadTime {
+			t.Errorf("%s: LeadTime = %v, %v, want %v", test.guid, d, ok, test.leadTime)
+		}
+	}
+}
+
+func TestGroupFills(t *testing.T) {
+	start := time.Date(2026, time.March, 2, 18, 0, 0, 0, time.UTC)
+	fill := func(center string, firstSeenHours, fullAtHours int) Fill {
+		f := Fill{
+			Latest:    Snapshot{CenterID: center, StartTimeUtc: start},
+			FirstSeen: start.Add(-time.Duration(firstSeenHours) * time.Hour),
+		}
+		if fullAtHours > 0 {
+			f.FullAt = start.Add(-time.Duration(fullAtHours) * time.Hour)
+		}
+		return f
+	}
+	fills := []Fill{
+		fill("b", 72, 48), // 24h to full, 48h lead
+		fill("a", 72, 0),
+		fill("b", 72, 24), // 48h to full, 24h lead
+		fill("b", 72, 0),
+		fill("a", 72, 71), // 1h to full, 71h lead
+	}
+
+	stats := GroupFills(fills, func(f Fill) string { return f.Latest.CenterID })
+	if len(stats) != 2 || stats[0].Key != "a" || stats[1].Key != "b" {
+		t.Fatalf("groups = %+v, want a then b", stats)
+	}
+
+	a, b := stats[0], stats[1]
+	if a.Sessions != 2 || a.Filled != 1 || a.FillRate() != 0.5 || a.MedianTimeToFull != time.Hour || a.MedianLeadTime != 71*time.Hour {
+		t.Errorf("a = %+v", a)
+	}
+	// Even counts take the mean of the middle two
+	if b.Sessions != 3 || b.Filled != 2 || b.MedianTimeToFull != 36*time.Hour || b.MedianLeadTime != 36*time.Hour {
+		t.Errorf("b = %+v", b)
+	}
+	if (FillStats{}).FillRate() != 0 {
+		t.Error("FillRate of no sessions is not 0")
+	}
+}
@@ -0,0 +1,76 @@
+package ical
+
+import (
+	"slices"
+	"time"
+)
+
+// Period is a span of busy time
+type Period struct {
+	Start   time.Time
+	End     time.Time
+	Summary string
+}
+
+// Overlaps reports whether the period intersects [start, end)
+func (p Period) Overlaps(start, end time.Time) bool {
+	return p.Start.Before(end) && start.Before(p.End)
+}
+
+// Busy returns the periods between from and to blocked by events, with
+// recurring events expanded. Cancelled and transparent events are ignored.
+func (c *Calendar) Busy(from, to time.Time) []Period {
+	// Overrides of single occurrences, keyed by UID and original start
+	type key struct {
+		uid   string
+		start int64
+	}
+	overridden := map[key]bool{}
+	for _, event := range c.Events {
+		if !event.RecurrenceID.IsZero() {
+			overridden[key{event.UID, event.RecurrenceID.Unix()}] = true
+		}
+	}
+
+	var busy []Period
+	add := func(event Event, start, end time.Time) {
+		if event.Status == "CANCELLED" || event.Transparent {
+			return
+		}
+		period := Period{Start: start, End: end, Summary: event.Summary}
+		if period.Overlaps(from, to) {
+			busy = append(busy, period)
+		}
+	}
+
+	for _, event := range c.Events {
+		length := event.End.Sub(event.Start)
+		if !event.RecurrenceID.IsZero() {
+			add(event, event.Start, event.End)
+			continue
+		}
+
+		// Occurrences come from the rule, if any, plus RDATEs, which an
+		// event can have without a rule
+		starts := []time.Time{event.Start}
+		if event.Rule != nil {
+			starts = event.Rule.Occurrences(event.Start, to)
+		}
+		starts = append(starts, event.RDates...)
+		occurrences := make([]Period, 0, len(starts)+len(event.RPeriods))
+		for _, start := range starts {
+			occurrences = append(occurrences, Period{Start: start, End: start.Add(length)})
+		}
+		occurrences = append(occurrences, event.RPeriods...)
+
+		for _, occurrence := range occurrences {
+			if overridden[key{event.UID, occurrence.Start.Unix()}] || slices.ContainsFunc(event.ExDates, occurrence.Start.Equal) {
+				continue
+			}
+			add(event, occurrence.Start, occurrence.End)
+		}
+	}
+
+	slices.SortFunc(busy, func(a, b Period) int { return a.Start.Compare(b.Start) })
+	return busy
+}
@@ -0,0 +1,170 @@
+package ical
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// busyPeriods parses a calendar and returns the busy periods in March 2026 as
+// "start-end" strings in Chicago time
+func busyPeriods(t *testing.T, events string) []string {
+	t.Helper()
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	text := "BEGIN:VCALENDAR\r\n" + strings.ReplaceAll(strings.TrimSpace(events), "\n", "\r\n") + "\r\nEND:VCALENDAR\r\n"
+	calendar, err := Parse(strings.NewReader(text), chicago)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, chicago)
+	to := time.Date(2026, time.April, 1, 0, 0, 0, 0, chicago)
+	var got []string
+	for _, period := range calendar.Busy(from, to) {
+		got = append(got, period.Start.In(chicago).Format("01-02 15:04")+"-"+period.End.In(chicago).Format("15:04"))
+	}
+	slices.Sort(got)
+	return got
+}
+
+func TestBusy(t *testing.T) {
+	tests := []struct {
+		name   string
+		events string
+		want   []string
+	}{
+		{
+			name: "single event",
+			events: `
+BEGIN:VEVENT
+UID:a
+DTSTART;TZID=America/Chicago:20260310T090000
+DTEND;TZID=America/Chicago:20260310T100000
+END:VEVENT`,
+			want: []string{"03-10 09:00-10:00"},
+		},
+		{
+			name: "exdate removes an occurrence",
+			events: `
+BEGIN:VEVENT
+UID:a
+DTSTART;TZID=America/Chicago:20260302T180000
+DURATION:PT1H
+RRULE:FREQ=WEEKLY;COUNT=4
+EXDATE;TZID=America/Chicago:20260309T180000
+END:VEVENT`,
+			want: []string{"03-02 18:00-19:00", "03-16 18:00-19:00", "03-23 18:00-19:00"},
+		},
+		{
+			name: "override moves an occurrence",
+			events: `
+BEGIN:VEVENT
+UID:a
+DTSTART;TZID=America/Chicago:20260302T180000
+DURATION:PT1H
+RRULE:FREQ=WEEKLY;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:a
+RECURRENCE-ID;TZID=America/Chicago:20260309T180000
+DTSTART;TZID=America/Chicago:20260310T070000
+DTEND;TZID=America/Chicago:20260310T080000
+END:VEVENT`,
+			want: []string{"03-02 18:00-19:00", "03-10 07:00-08:00", "03-16 18:00-19:00"},
+		},
+		{
+			name: "cancelled override removes an occurrence",
+			events: `
+BEGIN:VEVENT
+UID:a
+DTSTART;TZID=America/Chicago:20260302T180000
+DURATION:PT1H
+RRULE:FREQ=WEEKLY;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:a
+RECURRENCE-ID;TZID=America/Chicago:20260309T180000
+DTSTART;TZID=America/Chicago:20260309T180000
+DURATION:PT1H
+STATUS:CANCELLED
+END:VEVENT`,
+			want: []string{"03-02 18:00-19:00", "03-16 18:00-19:00"},
+		},
+		{
+			name: "rdate without rrule",
+			events: `
+BEGIN:VEVENT
+UID:a
+DTSTART;TZID=America/Chicago:20260303T120000
+DURATION:PT30M
+RDATE;TZID=America/Chicago:20260305T120000,20260319T130000
+END:VEVENT`,
+			want: []string{"03-03 12:00-12:30", "03-05 12:00-12:30", "03-19 13:00-13:30"},
+		},
+		{
+			name: "rdate periods",
+			events: `
+BEGIN:VEVENT
+UID:a
+DTSTART;TZID=America/Chicago:20260303T120000
+DURATION:PT30M
+RDATE;VALUE=PERIOD:20260304T150000Z/20260304T170000Z,20260306T150000Z/PT45M
+END:VEVENT`,
+			want: []string{"03-03 12:00-12:30", "03-04 09:00-11:00", "03-06 09:00-09:45"},
+		},
+		{
+			name: "windows zone name",
+			events: `
+BEGIN:VEVENT
+UID:a
+DTSTART;TZID=Eastern Standard Time:20260310T090000
+DTEND;TZID=Eastern Standard Time:20260310T100000
+END:VEVENT`,
+			want: []string{"03-10 08:00-09:00"},
+		},
+		{
+			name: "transparent events are free",
+			events: `
+BEGIN:VEVENT
+UID:a
+DTSTART;TZID=America/Chicago:20260310T090000
+DTEND;TZID=America/Chicago:20260310T100000
+TRANSP:TRANSPARENT
+END:VEVENT`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := busyPeriods(t, test.events); !slices.Equal(got, test.want) {
+				t.Errorf("Busy = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadZone(t *testing.T) {
+	fallback := time.FixedZone("fallback", -6*3600)
+	tests := []struct {
+		tzid string
+		want string
+	}{
+		{"America/Chicago", "America/Chicago"},
+		{"Central Standard Time", "America/Chicago"},
+		{"/mozilla.org/20050126_1/America/New_York", "America/New_York"},
+		{"Nowhere Standard Time", "fallback"},
+	}
+	for _, test := range tests {
+		if _, err := time.LoadLocation(test.want); err != nil && test.want != "fallback" {
+			t.Skipf("tzdata unavailable: %v", err)
+		}
+		if got := loadZone(test.tzid, fallback).String(); got != test.want {
+			t.Errorf("loadZone(%q) = %s, want %s", test.tzid, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Event is a VEVENT. Recurring events keep their rule and are expanded by
+// Calendar.Busy.
+type Event struct {
+	UID          string
+	Summary      string
+	Location     string
+	Description  string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	Status       string // TENTATIVE, CONFIRMED or CANCELLED
+	Transparent  bool   // TRANSP:TRANSPARENT, the event does not block time
+	Rule         *Rule
+	ExDates      []time.Time
+	RDates       []time.Time
+	RPeriods     []Period  // RDATE;VALUE=PERIOD occurrences, each with its own end
+	RecurrenceID time.Time // Set on an override of a single occurrence
+}
+
+// Calendar is a set of events read from one or more files
+type Calendar struct {
+	Events []Event
+}
+
+// property is a single content line, e.g. DTSTART;TZID=America/Chicago:20250101T090000
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Load reads calendars from ICS files or directories of ICS files, as written
+// by CalDAV clients that export one file per event. Floating times are read in
+// location.
+func Load(location *time.Location, paths ...string) (*Calendar, error) {
+	calendar := &Calendar{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading calendar: %v", err)
+		}
+
+		files := []string{path}
+		if info.IsDir() {
+			files = nil
+			err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && strings.EqualFold(filepath.Ext(file), ".ics") {
+					files = append(files, file)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error reading calendar directory %s: %v", path, err)
+			}
+		}
+
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return nil, fmt.Errorf("error reading calendar: %v", err)
+			}
+			parsed, err := Parse(f, location)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("error parsing calendar %s: %v", file, err)
+			}
+			calendar.Events = append(calendar.Events, parsed.Events...)
+		}
+	}
+	return calendar, nil
+}
+
+// Parse reads the events of an iCalendar stream. Floating times and dates are
+// read in location.
+func Parse(r io.Reader, location *time.Location) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &Calendar{}
+	var event *Event
+	var duration time.Duration
+	depth := 0 // Nesting inside the VEVENT, e.g. VALARM
+
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		switch {
+		case prop.name == "BEGIN" && prop.value == "VEVENT":
+			event = &Event{}
+			duration = 0
+			continue
+		case prop.name == "END" && prop.value == "VEVENT":
+			if event == nil {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN", i+1)
+			}
+			if event.End.IsZero() {
+				switch {
+				case duration > 0:
+					event.End = event.Start.Add(duration)
+				case event.AllDay:
+					event.End = event.Start.AddDate(0, 0, 1)
+				default:
+					event.End = event.Start
+				}
+			}
+			if !event.Start.IsZero() {
+				calendar.Events = append(calendar.Events, *event)
+			}
+			event = nil
+			continue
+		case event == nil:
+			continue
+		case prop.name == "BEGIN":
+			depth++
+			continue
+		case prop.name == "END":
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch prop.name {
+		case "UID":
+			event.UID = prop.value
+		case "SUMMARY":
+			event.Summary = unescape(prop.value)
+		case "LOCATION":
+			event.Location = unescape(prop.value)
+		case "DESCRIPTION":
+			event.Description = unescape(prop.value)
+		case "STATUS":
+			event.Status = strings.ToUpper(prop.value)
+		case "TRANSP":
+			event.Transparent = strings.EqualFold(prop.value, "TRANSPARENT")
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseTime(prop, location)
+		case "DTEND":
+			event.End, _, err = parseTime(prop, location)
+		case "DURATION":
+			duration, err = parseDuration(prop.value)
+		case "RRULE":
+			event.Rule, err = ParseRule(prop.value, location)
+		case "EXDATE":
+			var dates []time.Time
+			dates, err = parseTimes(prop, location)
+			event.ExDates = append(event.ExDates, dates...)
+		case "RDATE":
+			if strings.EqualFold(prop.params["VALUE"], "PERIOD") {
+				var periods []Period
+				periods, err = parsePeriods(prop, location)
+				event.RPeriods = append(event.RPeriods, periods...)
+				break
+			}
+			var dates []time.Time
+			dates, err = parseTimes(prop, location)
+			event.RDates = append(event.RDates, dates...)
+		case "RECURRENCE-ID":
+			event.RecurrenceID, _, err = parseTime(prop, location)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", i+1, prop.name, err)
+		}
+	}
+
+	return calendar, nil
+}
+
+// unfold joins continuation lines, which start with a space or tab
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading calendar: %v", err)
+	}
+	return lines, nil
+}
+
+func parseProperty(line string) (property, error) {
+	// The value starts at the first colon outside a quoted parameter
+	colon := -1
+	quoted := false
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("malformed content line %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := property{
+		name:   strings.ToUpper(parts[0]),
+		params: map[string]string{},
+		value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return prop, nil
+}
+
+// parseTime reads a DATE or DATE-TIME value, returning whether it was a date
+func parseTime(prop property, location *time.Location) (time.Time, bool, error) {
+	times, err := parseTimes(prop, location)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if len(times) == 0 {
+		return time.Time{}, false, fmt.Errorf("missing value")
+	}
+	isDate := prop.params["VALUE"] == "DATE" || len(prop.value) == len("20060102")
+	return times[0], isDate, nil
+}
+
+// parseTimes reads a comma separated list of DATE or DATE-TIME values
+func parseTimes(prop property, location *time.Location) ([]time.Time, error) {
+	if tzid, ok := prop.params["TZID"]; ok {
+		location = loadZone(tzid, location)
+	}
+
+	var times []time.Time
+	for _, value := range strings.Split(prop.value, ",") {
+		t, err := parseValue(value, location)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// parsePeriods reads a comma separated list of PERIOD values, each a start
+// and either an end or a duration, e.g. 20250101T090000Z/PT1H
+func parsePeriods(prop property, location *time.Location) ([]Period, error) {
+	if tzid, ok := prop.params["TZID"]; ok {
+		location = loadZone(tzid, location)
+	}
+
+	var periods []Period
+	for _, value := range strings.Split(prop.value, ",") {
+		startValue, endValue, ok := strings.Cut(strings.TrimSpace(value), "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid period %q", value)
+		}
+		start, err := parseValue(startValue, location)
+		if err != nil {
+			return nil, err
+		}
+		var end time.Time
+		if strings.HasPrefix(endValue, "P") || strings.HasPrefix(endValue, "+P") {
+			duration, err := parseDuration(endValue)
+			if err != nil {
+				return nil, err
+			}
+			end = start.Add(duration)
+		} else if end, err = parseValue(endValue, location); err != nil {
+			return nil, err
+		}
+		periods = append(periods, Period{Start: start, End: end})
+	}
+	return periods, nil
+}
+
+func parseValue(value string, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case len(value) == len("20060102"):
+		return time.ParseInLocation("20060102", value, location)
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	default:
+		return time.ParseInLocation("20060102T150405", value, location)
+	}
+}
+
+// parseDuration reads a DURATION value such as PT1H30M or P1D
+func parseDuration(value string) (time.Duration, error) {
+	negative := false
+	switch {
+	case strings.HasPrefix(value, "-"):
+		negative = true
+		value = value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var total time.Duration
+	number := 0
+	inTime := false
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			number = number*10 + int(r-'0')
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		case r == 'W':
+			total += time.Duration(number) * 7 * 24 * time.Hour
+		case r == 'D':
+			total += time.Duration(number) * 24 * time.Hour
+		case r == 'H' && inTime:
+			total += time.Duration(number) * time.Hour
+		case r == 'M' && inTime:
+			total += time.Duration(number) * time.Minute
+		case r == 'S' && inTime:
+			total += time.Duration(number) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number = 0
+	}
+
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
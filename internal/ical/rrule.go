@@ -0,0 +1,255 @@
+package ical
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxOccurrences bounds how many occurrences a rule expands to, so that a rule
+// that never matches cannot loop forever
+const maxOccurrences = 100000
+
+// Rule is a recurrence rule (RRULE). BYSETPOS, BYWEEKNO, BYYEARDAY and the
+// sub-daily frequencies are not supported.
+type Rule struct {
+	Freq       string // DAILY, WEEKLY, MONTHLY or YEARLY
+	Interval   int
+	Count      int       // 0 means no limit
+	Until      time.Time // Zero means no limit
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+// WeekdayNum is a BYDAY entry such as MO, or 2TU / -1FR for the second Tuesday
+// or last Friday of a month or year
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRule reads an RRULE value such as FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10
+func ParseRule(value string, location *time.Location) (*Rule, error) {
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			rule.Until, err = parseValue(val, location)
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				code := strings.ToUpper(day[max(len(day)-2, 0):])
+				weekday, ok := weekdayCodes[code]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				n := 0
+				if prefix := day[:len(day)-2]; prefix != "" {
+					if n, err = strconv.Atoi(prefix); err != nil {
+						return nil, fmt.Errorf("invalid BYDAY %q", day)
+					}
+				}
+				rule.ByDay = append(rule.ByDay, WeekdayNum{N: n, Weekday: weekday})
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, month := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(month)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", month)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", key, val)
+		}
+	}
+
+	switch rule.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported FREQ %q", rule.Freq)
+	}
+	if rule.Interval < 1 {
+		rule.Interval = 1
+	}
+	return rule, nil
+}
+
+// Occurrences returns the start times generated by the rule for an event
+// starting at start, up to and including end. The event's own start is always
+// the first occurrence and counts towards COUNT.
+func (r *Rule) Occurrences(start, end time.Time) []time.Time {
+	if start.After(end) {
+		return nil
+	}
+	occurrences := []time.Time{start}
+	count := 1
+
+	for period := 0; period <= maxOccurrences; period++ {
+		for _, candidate := range r.period(start, period) {
+			if !candidate.After(start) {
+				continue
+			}
+			if candidate.After(end) || (!r.Until.IsZero() && candidate.After(r.Until)) {
+				return occurrences
+			}
+			if r.Count > 0 && count >= r.Count {
+				return occurrences
+			}
+			count++
+			occurrences = append(occurrences, candidate)
+		}
+	}
+	return occurrences
+}
+
+// period returns the sorted candidate starts in the n-th interval after start
+func (r *Rule) period(start time.Time, n int) []time.Time {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	var candidates []time.Time
+	switch r.Freq {
+	case "DAILY":
+		day := start.AddDate(0, 0, n*r.Interval)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			candidates = append(candidates, day)
+		}
+
+	case "WEEKLY":
+		// Weeks start on Monday
+		offset := (int(start.Weekday()) + 6) % 7
+		weekStart := at(start.Year(), start.Month(), start.Day()-offset).AddDate(0, 0, 7*n*r.Interval)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []WeekdayNum{{Weekday: start.Weekday()}}
+		}
+		for _, day := range days {
+			candidate := weekStart.AddDate(0, 0, (int(day.Weekday)+6)%7)
+			if r.matchesMonth(candidate) {
+				candidates = append(candidates, candidate)
+			}
+		}
+
+	case "MONTHLY":
+		first := at(start.Year(), start.Month()+time.Month(n*r.Interval), 1)
+		if r.matchesMonth(first) {
+			candidates = r.daysInMonth(first, start)
+		}
+
+	case "YEARLY":
+		year := start.Year() + n*r.Interval
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, month := range months {
+			candidates = append(candidates, r.daysInMonth(at(year, month, 1), start)...)
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b time.Time) int { return a.Compare(b) })
+	return candidates
+}
+
+// daysInMonth returns the matching days of the month starting at first
+func (r *Rule) daysInMonth(first, start time.Time) []time.Time {
+	lastDay := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, n := range r.ByMonthDay {
+			day := n
+			if n < 0 {
+				day = lastDay + n + 1
+			}
+			if day >= 1 && day <= lastDay {
+				candidate := first.AddDate(0, 0, day-1)
+				if r.matchesWeekday(candidate) {
+					days = append(days, candidate)
+				}
+			}
+		}
+
+	case len(r.ByDay) > 0:
+		for _, byDay := range r.ByDay {
+			var matching []time.Time
+			for day := 1; day <= lastDay; day++ {
+				candidate := first.AddDate(0, 0, day-1)
+				if candidate.Weekday() == byDay.Weekday {
+					matching = append(matching, candidate)
+				}
+			}
+			switch {
+			case byDay.N == 0:
+				days = append(days, matching...)
+			case byDay.N > 0 && byDay.N <= len(matching):
+				days = append(days, matching[byDay.N-1])
+			case byDay.N < 0 && -byDay.N <= len(matching):
+				days = append(days, matching[len(matching)+byDay.N])
+			}
+		}
+
+	default:
+		if start.Day() <= lastDay {
+			days = append(days, first.AddDate(0, 0, start.Day()-1))
+		}
+	}
+
+	return days
+}
+
+func (r *Rule) matchesMonth(t time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, t.Month())
+}
+
+func (r *Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, n := range r.ByMonthDay {
+		if n == t.Day() || (n < 0 && lastDay+n+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, day := range r.ByDay {
+		if day.Weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
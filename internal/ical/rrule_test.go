@@ -0,0 +1,161 @@
+package ical
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOccurrences(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	at := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, chicago)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		end   string
+		want  []string
+	}{
+		{
+			name:  "weekly by day",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE",
+			start: "2026-03-02 18:00", // Monday
+			end:   "2026-03-12 00:00",
+			want:  []string{"2026-03-02 18:00", "2026-03-04 18:00", "2026-03-09 18:00", "2026-03-11 18:00"},
+		},
+		{
+			name:  "weekly keeps wall clock across spring forward",
+			rule:  "FREQ=WEEKLY",
+			start: "2026-03-01 09:00",
+			end:   "2026-03-15 23:00",
+			want:  []string{"2026-03-01 09:00", "2026-03-08 09:00", "2026-03-15 09:00"},
+		},
+		{
+			name:  "biweekly",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+			start: "2026-03-03 07:00",
+			end:   "2026-04-01 00:00",
+			want:  []string{"2026-03-03 07:00", "2026-03-17 07:00", "2026-03-31 07:00"},
+		},
+		{
+			name:  "monthly last friday",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			start: "2026-01-30 12:00",
+			end:   "2026-05-01 00:00",
+			want:  []string{"2026-01-30 12:00", "2026-02-27 12:00", "2026-03-27 12:00", "2026-04-24 12:00"},
+		},
+		{
+			name:  "monthly second tuesday",
+			rule:  "FREQ=MONTHLY;BYDAY=2TU",
+			start: "2026-01-13 19:00",
+			end:   "2026-03-31 00:00",
+			want:  []string{"2026-01-13 19:00", "2026-02-10 19:00", "2026-03-10 19:00"},
+		},
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: "2026-01-31 08:00",
+			end:   "2026-06-01 00:00",
+			want:  []string{"2026-01-31 08:00", "2026-03-31 08:00", "2026-05-31 08:00"},
+		},
+		{
+			name:  "count includes the first occurrence",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: "2026-03-07 06:00",
+			end:   "2026-12-31 00:00",
+			want:  []string{"2026-03-07 06:00", "2026-03-08 06:00", "2026-03-09 06:00"},
+		},
+		{
+			name:  "count with by day",
+			rule:  "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=4",
+			start: "2026-03-03 17:30",
+			end:   "2026-12-31 00:00",
+			want:  []string{"2026-03-03 17:30", "2026-03-05 17:30", "2026-03-10 17:30", "2026-03-12 17:30"},
+		},
+		{
+			name:  "until is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20260305T060000",
+			start: "2026-03-03 06:00",
+			end:   "2026-12-31 00:00",
+			want:  []string{"2026-03-03 06:00", "2026-03-04 06:00", "2026-03-05 06:00"},
+		},
+		{
+			name:  "until in UTC",
+			rule:  "FREQ=WEEKLY;UNTIL=20260317T000000Z",
+			start: "2026-03-02 18:00",
+			end:   "2026-12-31 00:00",
+			want:  []string{"2026-03-02 18:00", "2026-03-09 18:00", "2026-03-16 18:00"},
+		},
+		{
+			name:  "end bounds expansion",
+			rule:  "FREQ=DAILY",
+			start: "2026-03-01 10:00",
+			end:   "2026-03-03 10:00",
+			want:  []string{"2026-03-01 10:00", "2026-03-02 10:00", "2026-03-03 10:00"},
+		},
+		{
+			name:  "yearly by month",
+			rule:  "FREQ=YEARLY;BYMONTH=1,7",
+			start: "2026-01-15 09:00",
+			end:   "2027-02-01 00:00",
+			want:  []string{"2026-01-15 09:00", "2026-07-15 09:00", "2027-01-15 09:00"},
+		},
+		{
+			name:  "start after end",
+			rule:  "FREQ=DAILY",
+			start: "2026-03-05 10:00",
+			end:   "2026-03-01 00:00",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := ParseRule(test.rule, chicago)
+			if err != nil {
+				t.Fatalf("ParseRule(%q): %v", test.rule, err)
+			}
+
+			var got []string
+			for _, occurrence := range rule.Occurrences(at(test.start), at(test.end)) {
+				got = append(got, occurrence.In(chicago).Format("2006-01-02 15:04"))
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Occurrences = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("FREQ=monthly;INTERVAL=2;BYDAY=-1FR,2tu;BYMONTH=3;COUNT=5", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Freq != "MONTHLY" || rule.Interval != 2 || rule.Count != 5 {
+		t.Errorf("rule = %+v", rule)
+	}
+	want := []WeekdayNum{{N: -1, Weekday: time.Friday}, {N: 2, Weekday: time.Tuesday}}
+	if !slices.Equal(rule.ByDay, want) {
+		t.Errorf("ByDay = %v, want %v", rule.ByDay, want)
+	}
+	if !slices.Equal(rule.ByMonth, []time.Month{time.March}) {
+		t.Errorf("ByMonth = %v", rule.ByMonth)
+	}
+
+	for _, value := range []string{"FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;COUNT=many", "FREQ=YEARLY;BYMONTH=13"} {
+		if _, err := ParseRule(value, time.UTC); err == nil {
+			t.Errorf("ParseRule(%q) succeeded, want error", value)
+		}
+	}
+}
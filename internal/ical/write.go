@@ -0,0 +1,100 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxLineOctets is the longest a content line may be before it is folded
+const maxLineOctets = 75
+
+// Write renders events as an iCalendar stream. stamp is used as DTSTAMP.
+func Write(w io.Writer, prodID, name string, events []Event, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if name != "" {
+		line("X-WR-CALNAME", escape(name))
+	}
+
+	for _, event := range events {
+		writeEvent(bw, event, stamp)
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// WriteEvent renders a single event as a complete iCalendar object, the form
+// CalDAV servers store events in
+func WriteEvent(w io.Writer, prodID string, event Event, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	writeFolded(bw, "BEGIN:VCALENDAR")
+	writeFolded(bw, "VERSION:2.0")
+	writeFolded(bw, "PRODID:"+prodID)
+	writeEvent(bw, event, stamp)
+	writeFolded(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+func writeEvent(bw *bufio.Writer, event Event, stamp time.Time) {
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VEVENT")
+	line("UID", event.UID)
+	line("DTSTAMP", formatUTC(stamp))
+	line("DTSTART", formatUTC(event.Start))
+	line("DTEND", formatUTC(event.End))
+	line("SUMMARY", escape(event.Summary))
+	if event.Location != "" {
+		line("LOCATION", escape(event.Location))
+	}
+	if event.Description != "" {
+		line("DESCRIPTION", escape(event.Description))
+	}
+	if event.Status != "" {
+		line("STATUS", event.Status)
+	}
+	line("END", "VEVENT")
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// writeFolded writes a content line, folding it into 75 octet chunks without
+// splitting UTF-8 sequences
+func writeFolded(bw *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		bw.WriteString(line[:cut])
+		bw.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1 // Continuation lines start with a space
+	}
+	bw.WriteString(line)
+	bw.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
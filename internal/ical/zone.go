@@ -0,0 +1,83 @@
+package ical
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// windowsZones maps the Windows time zone names used by Outlook and Exchange
+// to IANA names
+var windowsZones = map[string]string{
+	"Dateline Standard Time":         "Etc/GMT+12",
+	"Hawaiian Standard Time":         "Pacific/Honolulu",
+	"Alaskan Standard Time":          "America/Anchorage",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"US Mountain Standard Time":      "America/Phoenix",
+	"Mountain Standard Time":         "America/Denver",
+	"Central Standard Time":          "America/Chicago",
+	"Central America Standard Time":  "America/Guatemala",
+	"Canada Central Standard Time":   "America/Regina",
+	"Central Standard Time (Mexico)": "America/Mexico_City",
+	"Eastern Standard Time":          "America/New_York",
+	"US Eastern Standard Time":       "America/Indiana/Indianapolis",
+	"Atlantic Standard Time":         "America/Halifax",
+	"Newfoundland Standard Time":     "America/St_Johns",
+	"SA Pacific Standard Time":       "America/Bogota",
+	"E. South America Standard Time": "America/Sao_Paulo",
+	"Argentina Standard Time":        "America/Buenos_Aires",
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"Greenwich Standard Time":        "Atlantic/Reykjavik",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"Romance Standard Time":          "Europe/Paris",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"GTB Standard Time":              "Europe/Bucharest",
+	"FLE Standard Time":              "Europe/Kiev",
+	"Russian Standard Time":          "Europe/Moscow",
+	"South Africa Standard Time":     "Africa/Johannesburg",
+	"Israel Standard Time":           "Asia/Jerusalem",
+	"Arabian Standard Time":          "Asia/Dubai",
+	"India Standard Time":            "Asia/Calcutta",
+	"SE Asia Standard Time":          "Asia/Bangkok",
+	"China Standard Time":            "Asia/Shanghai",
+	"Singapore Standard Time":        "Asia/Singapore",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"Korea Standard Time":            "Asia/Seoul",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"E. Australia Standard Time":     "Australia/Brisbane",
+	"Cen. Australia Standard Time":   "Australia/Adelaide",
+	"W. Australia Standard Time":     "Australia/Perth",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+}
+
+// warnedZones remembers zones already warned about, so a calendar full of
+// events in one unknown zone logs once
+var warnedZones sync.Map
+
+// loadZone resolves a TZID, accepting IANA names, Windows names and
+// prefixed names like /mozilla.org/20050126_1/America/Chicago. Unknown zones
+// fall back to fallback with a warning.
+func loadZone(tzid string, fallback *time.Location) *time.Location {
+	name := tzid
+	if iana, ok := windowsZones[name]; ok {
+		name = iana
+	}
+	if location, err := time.LoadLocation(name); err == nil {
+		return location
+	}
+	// Some clients prefix the IANA name with a vendor path
+	if parts := strings.Split(strings.Trim(name, "/"), "/"); len(parts) > 2 {
+		if location, err := time.LoadLocation(strings.Join(parts[len(parts)-2:], "/")); err == nil {
+			return location
+		}
+	}
+
+	if _, warned := warnedZones.LoadOrStore(tzid, true); !warned {
+		slog.Warn("Unknown calendar time zone, reading its times in the profile's zone", "tzid", tzid, "zone", fallback.String())
+	}
+	return fallback
+}
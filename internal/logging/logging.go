@@ -0,0 +1,212 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Options configure the logger built by Setup
+type Options struct {
+	Level  slog.Level
+	Format string // "text" or "json"
+	RunID  string // Logged with every record that has no run ID in its context
+}
+
+// OptionsFromEnv reads COREPOWER_LOG_LEVEL (debug, info, warn or error) and
+// COREPOWER_LOG_FORMAT (text or json)
+func OptionsFromEnv() (Options, error) {
+	opts := Options{Level: slog.LevelInfo, Format: "text"}
+	if level := os.Getenv("COREPOWER_LOG_LEVEL"); level != "" {
+		if err := opts.Level.UnmarshalText([]byte(level)); err != nil {
+			return opts, fmt.Errorf("invalid COREPOWER_LOG_LEVEL %q", level)
+		}
+	}
+	if format := os.Getenv("COREPOWER_LOG_FORMAT"); format != "" {
+		if format != "text" && format != "json" {
+			return opts, fmt.Errorf("invalid COREPOWER_LOG_FORMAT %q, expected text or json", format)
+		}
+		opts.Format = format
+	}
+	return opts, nil
+}
+
+// New builds a logger writing to w that redacts secrets and tags records
+// with their run ID
+func New(w io.Writer, opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level, ReplaceAttr: redactAttr}
+	var handler slog.Handler
+	if opts.Format == "json" {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	} else {
+		handler = slog.NewTextHandler(w, handlerOpts)
+	}
+	return slog.New(&runHandler{Handler: handler, runID: opts.RunID})
+}
+
+// Setup makes a logger writing to stderr the default for slog and the log
+// package
+func Setup(opts Options) *slog.Logger {
+	logger := New(os.Stderr, opts)
+	slog.SetDefault(logger)
+	return logger
+}
+
+type runIDKey struct{}
+
+// WithRunID returns a context whose log records are tagged with id
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunID returns the run ID of the context, if any
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// NewID returns a random ID for correlating the records of one run
+func NewID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// runHandler adds the run_id attribute, from the record's context or the
+// process wide default
+type runHandler struct {
+	slog.Handler
+	runID string
+}
+
+func (h *runHandler) Handle(ctx context.Context, r slog.Record) error {
+	id := h.runID
+	if ctx != nil {
+		if ctxID := RunID(ctx); ctxID != "" {
+			id = ctxID
+		}
+	}
+	if id != "" {
+		r.AddAttrs(slog.String("run_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *runHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &runHandler{Handler: h.Handler.WithAttrs(attrs), runID: h.runID}
+}
+
+func (h *runHandler) WithGroup(name string) slog.Handler {
+	return &runHandler{Handler: h.Handler.WithGroup(name), runID: h.runID}
+}
+
+const redacted = "[REDACTED]"
+
+// secretKeys are attribute names whose values are never logged
+var secretKeys = []string{"password", "token", "secret", "authorization", "api_key", "apikey"}
+
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@([A-Za-z0-9\-]+\.)+[A-Za-z]{2,}`)
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`)
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/\-]+=*`)
+)
+
+// redactAttr hides secrets by attribute or group name, and tokens and email
+// addresses inside any string, the message included. Structs, maps and slices
+// are redacted field by field.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if isSecret(a.Key) || slices.ContainsFunc(groups, isSecret) {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		return slog.Any(a.Key, redactValue(a.Value.Any()))
+	}
+	return a
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue redacts a value of any other kind. Errors and values with a
+// string form are logged as that string, anything else is logged as its JSON
+// with secret fields hidden.
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case error:
+		return Redact(v.Error())
+	case fmt.Stringer:
+		return Redact(v.String())
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return redacted
+		}
+		return Redact(string(text))
+	case []byte:
+		return Redact(string(v))
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return Redact(fmt.Sprintf("%+v", v))
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return Redact(string(data))
+	}
+	return redactJSON(decoded)
+}
+
+// redactJSON redacts decoded JSON in place
+func redactJSON(v any) any {
+	switch v := v.(type) {
+	case string:
+		return Redact(v)
+	case []any:
+		for i := range v {
+			v[i] = redactJSON(v[i])
+		}
+	case map[string]any:
+		for key, value := range v {
+			if isSecret(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactJSON(value)
+			}
+		}
+	}
+	return v
+}
+
+// Redact masks tokens and email addresses in s. Emails keep their first
+// letter and domain, so runs for different accounts can be told apart.
+func Redact(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = bearerPattern.ReplaceAllString(s, "${1}"+redacted)
+	return emailPattern.ReplaceAllStringFunc(s, func(email string) string {
+		at := strings.IndexByte(email, '@')
+		return email[:1] + "***" + email[at:]
+	})
+}
@@ -0,0 +1,22 @@
+package metrics
+
+// Buckets for latencies in seconds, from 5ms to a minute
+var LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// The metrics recorded by the clients and commands
+var (
+	SearchDuration = Default.NewHistogram("corepower_search_duration_seconds",
+		"Time taken by class searches, including every page and retry.", LatencyBuckets)
+	SearchResults = Default.NewHistogram("corepower_search_results",
+		"Number of classes returned by a search.", []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000})
+	SearchErrors = Default.NewCounter("corepower_search_errors_total",
+		"Searches that failed.")
+	ReservationAttempts = Default.NewCounter("corepower_reservation_attempts_total",
+		"Reservation requests by outcome: booked, waitlisted, already_reserved, full, not_bookable or error.", "outcome")
+	AuthRefreshes = Default.NewCounter("corepower_auth_refreshes_total",
+		"Authentications with the CorePower identity provider by result: success or error.", "result")
+	SnipeReaction = Default.NewHistogram("corepower_snipe_reaction_seconds",
+		"Time from the booking window opening to the first reservation request.", LatencyBuckets)
+	SchedulerLag = Default.NewHistogram("corepower_scheduler_lag_seconds",
+		"How late scheduled runs woke up.", LatencyBuckets)
+)
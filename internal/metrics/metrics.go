@@ -0,0 +1,212 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics and renders them in the Prometheus text format, so
+// they can be scraped or read by tests without a Prometheus server
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default is the registry the package level metrics are registered in
+var Default = NewRegistry()
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteText writes every metric in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry, for mounting at /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// series tracks the label values of a metric, in the order first seen
+type series[T any] struct {
+	mu     sync.Mutex
+	labels []string
+	keys   []string
+	values map[string]T
+}
+
+func (s *series[T]) get(labelValues []string, create func() T) T {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: got %d label values for %d labels", len(labelValues), len(s.labels)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	value, ok := s.values[key]
+	if !ok {
+		if s.values == nil {
+			s.values = map[string]T{}
+		}
+		value = create()
+		s.values[key] = value
+		s.keys = append(s.keys, key)
+	}
+	return value
+}
+
+// labelString renders label pairs, with extra pairs such as le appended
+func (s *series[T]) labelString(key string, extra ...string) string {
+	var pairs []string
+	if len(s.labels) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", s.labels[i], value))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a value that only goes up, optionally split by labels
+type Counter struct {
+	name, help string
+	series     series[*float64]
+}
+
+// NewCounter registers a counter with the given label names
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, series: series[*float64]{labels: labels}}
+	r.register(c)
+	return c
+}
+
+// Inc adds one for the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v for the given label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.series.mu.Lock()
+	defer c.series.mu.Unlock()
+	*c.series.get(labelValues, func() *float64 { return new(float64) }) += v
+}
+
+// Value returns the count for the given label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.series.mu.Lock()
+	defer c.series.mu.Unlock()
+	return *c.series.get(labelValues, func() *float64 { return new(float64) })
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.series.mu.Lock()
+	defer c.series.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range c.series.keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.series.labelString(key), formatFloat(*c.series.values[key]))
+	}
+}
+
+// Histogram counts observations into buckets, optionally split by labels
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	series     series[*histogramValue]
+}
+
+type histogramValue struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the given upper bucket bounds
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	h := &Histogram{name: name, help: help, buckets: buckets, series: series[*histogramValue]{labels: labels}}
+	r.register(h)
+	return h
+}
+
+// Observe records a value for the given label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.series.mu.Lock()
+	defer h.series.mu.Unlock()
+	value := h.series.get(labelValues, func() *histogramValue {
+		return &histogramValue{counts: make([]uint64, len(h.buckets))}
+	})
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		value.counts[i]++
+	}
+	value.count++
+	value.sum += v
+}
+
+// Count returns the number of observations for the given label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.series.mu.Lock()
+	defer h.series.mu.Unlock()
+	value := h.series.get(labelValues, func() *histogramValue {
+		return &histogramValue{counts: make([]uint64, len(h.buckets))}
+	})
+	return value.count
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.series.mu.Lock()
+	defer h.series.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range h.series.keys {
+		value := h.series.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += value.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.series.labelString(key, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.series.labelString(key, "le", "+Inf"), value.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.series.labelString(key), formatFloat(value.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.series.labelString(key), value.count)
+	}
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	attempts := r.NewCounter("test_attempts_total", "Attempts by outcome.", "outcome")
+	errors := r.NewCounter("test_errors_total", "Errors.")
+	duration := r.NewHistogram("test_duration_seconds", "Durations.", []float64{1, 0.1, 0.5})
+	byCenter := r.NewHistogram("test_results", "Results by center.", []float64{10}, "center")
+
+	attempts.Inc("booked")
+	attempts.Add(2, "full")
+	attempts.Inc("booked")
+	errors.Inc()
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 2} {
+		duration.Observe(v)
+	}
+	byCenter.Observe(4, `South "Lamar"`)
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP test_attempts_total Attempts by outcome.
+# TYPE test_attempts_total counter
+test_attempts_total{outcome="booked"} 2
+test_attempts_total{outcome="full"} 2
+# HELP test_errors_total Errors.
+# TYPE test_errors_total counter
+test_errors_total 1
+# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 2
+test_duration_seconds_bucket{le="0.5"} 3
+test_duration_seconds_bucket{le="1"} 4
+test_duration_seconds_bucket{le="+Inf"} 5
+test_duration_seconds_sum 3.15
+test_duration_seconds_count 5
+# HELP test_results Results by center.
+# TYPE test_results histogram
+test_results_bucket{center="South \"Lamar\"",le="10"} 1
+test_results_bucket{center="South \"Lamar\"",le="+Inf"} 1
+test_results_sum{center="South \"Lamar\""} 4
+test_results_count{center="South \"Lamar\""} 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteText =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnobservedMetrics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Nothing yet.", "outcome")
+	r.NewHistogram("test_seconds", "Nothing yet.", LatencyBuckets)
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP test_total Nothing yet.\n# TYPE test_total counter\n" +
+		"# HELP test_seconds Nothing yet.\n# TYPE test_seconds histogram\n"
+	if b.String() != want {
+		t.Errorf("WriteText = %q, want %q", b.String(), want)
+	}
+}
+
+func TestValues(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounter("test_total", "Test.", "outcome")
+	histogram := r.NewHistogram("test_seconds", "Test.", LatencyBuckets)
+
+	counter.Add(1.5, "ok")
+	histogram.Observe(0.2)
+	histogram.Observe(120)
+	if got := counter.Value("ok"); got != 1.5 {
+		t.Errorf("Value = %v, want 1.5", got)
+	}
+	if got := histogram.Count(); got != 2 {
+		t.Errorf("Count = %d, want 2", got)
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	counter := NewRegistry().NewCounter("test_total", "Test.", "outcome")
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc without label values did not panic")
+		}
+	}()
+	counter.Inc()
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Test.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_total 1\n") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SinkConfig configures one notification sink
+type SinkConfig struct {
+	Type    string            `json:"type"` // webhook, slack, discord, ntfy or email
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"` // webhook only
+	Token   string            `json:"token,omitempty"`   // ntfy only
+
+	// Email settings
+	SMTP     string   `json:"smtp,omitempty"` // host:port
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+
+	// Events to send, all of them when empty
+	Events []EventType `json:"events,omitempty"`
+
+	// Templates keyed by event type or "default", overriding the built-in
+	// title and message
+	Templates map[string]Template `json:"templates,omitempty"`
+}
+
+// sink is a notifier whose payload can be rendered without sending it
+type sink interface {
+	Notifier
+	preview(event Event) (string, error)
+}
+
+// New builds a notifier sending to every configured sink
+func New(configs []SinkConfig) (Notifier, error) {
+	var notifiers Multi
+	for i, cfg := range configs {
+		s, err := newSink(i, cfg)
+		if err != nil {
+			return nil, err
+		}
+		var notifier Notifier = s
+		if len(cfg.Events) > 0 {
+			notifier = filtered{Notifier: notifier, types: cfg.Events}
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	return notifiers, nil
+}
+
+// Preview renders what the sink would send for the event, without sending it
+func Preview(cfg SinkConfig, event Event) (string, error) {
+	s, err := newSink(0, cfg)
+	if err != nil {
+		return "", err
+	}
+	return s.preview(event)
+}
+
+func newSink(i int, cfg SinkConfig) (sink, error) {
+	for _, event := range cfg.Events {
+		switch event {
+		case Booked, Waitlisted, Failed, NoMatch:
+		default:
+			return nil, fmt.Errorf("notification %d: unknown event %q, expected booked, waitlisted, failed or no_match", i+1, event)
+		}
+	}
+	for key := range cfg.Templates {
+		switch EventType(key) {
+		case Booked, Waitlisted, Failed, NoMatch, defaultTemplateKey:
+		default:
+			return nil, fmt.Errorf("notification %d: unknown template event %q", i+1, key)
+		}
+	}
+	renderer, err := NewRenderer(cfg.Templates)
+	if err != nil {
+		return nil, fmt.Errorf("notification %d: %v", i+1, err)
+	}
+
+	switch cfg.Type {
+	case "webhook", "slack", "discord", "ntfy":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("notification %d: %s needs a url", i+1, cfg.Type)
+		}
+	}
+
+	switch cfg.Type {
+	case "webhook":
+		return &Webhook{URL: cfg.URL, Headers: cfg.Headers, Renderer: renderer}, nil
+	case "slack":
+		return &Slack{URL: cfg.URL, Renderer: renderer}, nil
+	case "discord":
+		return &Discord{URL: cfg.URL, Renderer: renderer}, nil
+	case "ntfy":
+		return &Ntfy{URL: cfg.URL, Token: cfg.Token, Renderer: renderer}, nil
+	case "email":
+		if cfg.SMTP == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return nil, fmt.Errorf("notification %d: email needs smtp, from and to", i+1)
+		}
+		return &Email{Addr: cfg.SMTP, Username: cfg.Username, Password: cfg.Password, From: cfg.From, To: cfg.To, Renderer: renderer}, nil
+	default:
+		return nil, fmt.Errorf("notification %d: unknown type %q", i+1, cfg.Type)
+	}
+}
+
+// previewHeaders are headers holding the notification itself. Other headers
+// may carry credentials and are masked in previews.
+var previewHeaders = map[string]bool{"Title": true, "Tags": true, "Priority": true}
+
+// formatRequest renders an HTTP request the way it would go on the wire, with
+// credentials masked
+func formatRequest(url string, r request) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "POST %s\n", url)
+	fmt.Fprintf(&b, "Content-Type: %s\n", r.contentType)
+	keys := make([]string, 0, len(r.headers))
+	for key := range r.headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := r.headers[key]
+		if !previewHeaders[key] {
+			value = "[REDACTED]"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", key, value)
+	}
+	b.WriteString("\n")
+	b.Write(r.body)
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/corepower"
+)
+
+// EventType is what happened during a booking run
+type EventType string
+
+const (
+	Booked     EventType = "booked"
+	Waitlisted EventType = "waitlisted"
+	Failed     EventType = "failed"
+	NoMatch    EventType = "no_match"
+)
+
+// Event describes the outcome of a booking run
+type Event struct {
+	Type        EventType                      `json:"type"`
+	Time        time.Time                      `json:"time"`
+	User        string                         `json:"user,omitempty"`
+	Class       *corepower.Result              `json:"class,omitempty"`
+	Reservation *corepower.ReservationResponse `json:"reservation,omitempty"`
+	Candidates  []corepower.Result             `json:"candidates,omitempty"` // Ranked classes, best first
+	Error       string                         `json:"error,omitempty"`
+}
+
+// Title is a short headline for the event
+func (e Event) Title() string {
+	switch e.Type {
+	case Booked:
+		return "CorePower class booked"
+	case Waitlisted:
+		return "CorePower class waitlisted"
+	case NoMatch:
+		return "No CorePower class matched"
+	default:
+		return "CorePower booking failed"
+	}
+}
+
+// Message is a one or two line description of the event
+func (e Event) Message() string {
+	var class string
+	if e.Class != nil {
+		class = fmt.Sprintf("%s at %s in %s",
+			e.Class.ClassCategoryName,
+			e.Class.StartTime.Format("Mon Jan 2 3:04 PM MST"),
+			e.Class.CenterName)
+	}
+
+	switch e.Type {
+	case Booked:
+		return "Booked " + class
+	case Waitlisted:
+		return "Waitlisted for " + class
+	case NoMatch:
+		return "No class matched the preferences"
+	default:
+		if class != "" {
+			return fmt.Sprintf("Could not book %s: %s", class, e.Error)
+		}
+		return "Booking failed: " + e.Error
+	}
+}
+
+// Notifier delivers events somewhere people will see them
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Multi sends every event to all of its notifiers
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// filtered only passes on events of the given types
+type filtered struct {
+	Notifier
+	types []EventType
+}
+
+func (f filtered) Notify(ctx context.Context, event Event) error {
+	if !slices.Contains(f.types, event.Type) {
+		return nil
+	}
+	return f.Notifier.Notify(ctx, event)
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/geo"
+)
+
+// Samples returns one made up event of each type, for trying out templates
+func Samples(now time.Time) []Event {
+	location, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		location = time.UTC
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 18, 0, 0, 0, location).AddDate(0, 0, 14)
+
+	candidates := []corepower.Result{
+		{
+			AvailableSlots:    4,
+			CanBook:           "true",
+			CenterName:        "South Lamar",
+			ClassCategoryName: "Yoga Sculpt",
+			StartTime:         start,
+			StartTimeUtc:      start.UTC(),
+			EndTimeUtc:        start.Add(time.Hour).UTC(),
+			CenterID:          "1007",
+			SessionID:         123456,
+			Location:          geo.Point{Latitude: 30.2506, Longitude: -97.7663},
+			Preference:        1,
+			DistanceMiles:     1.2,
+			Score:             1.12,
+		},
+		{
+			AvailableSlots:    12,
+			CanBook:           "true",
+			CenterName:        "Domain",
+			ClassCategoryName: "Yoga Sculpt",
+			StartTime:         start.Add(30 * time.Minute),
+			StartTimeUtc:      start.Add(30 * time.Minute).UTC(),
+			EndTimeUtc:        start.Add(90 * time.Minute).UTC(),
+			CenterID:          "1017",
+			SessionID:         123789,
+			Location:          geo.Point{Latitude: 30.4021, Longitude: -97.7253},
+			Preference:        2,
+			DistanceMiles:     9.8,
+			Score:             2.98,
+		},
+	}
+	class := candidates[0]
+
+	reservation := corepower.ReservationResponse{
+		ID:                 987654,
+		StartTime:          start.Format("2006-01-02T15:04:05"),
+		EndTime:            start.Add(time.Hour).Format("2006-01-02T15:04:05"),
+		StartTimeUTC:       class.StartTimeUtc.Format("2006-01-02T15:04:05"),
+		EndTimeUTC:         class.EndTimeUtc.Format("2006-01-02T15:04:05"),
+		SessionName:        "Yoga Sculpt",
+		RegistrationStatus: corepower.RegistrationBooked,
+		Instructor:         "Jane Doe",
+		Center:             class.CenterName,
+		ClassName:          class.ClassCategoryName,
+		CanCancel:          true,
+		SessionID:          class.SessionID,
+		CenterID:           class.CenterID,
+		Status:             "Reserved",
+	}
+	waitlisted := reservation
+	waitlisted.Status = "Waitlisted"
+	waitlisted.RegistrationStatus = corepower.RegistrationWaitlisted
+	waitlisted.CurrentWaitlistPosition = 3
+
+	return []Event{
+		{Type: Booked, Time: now, User: "yogi@example.com", Class: &class, Reservation: &reservation, Candidates: candidates},
+		{Type: Waitlisted, Time: now, User: "yogi@example.com", Class: &class, Reservation: &waitlisted, Candidates: candidates},
+		{Type: Failed, Time: now, User: "yogi@example.com", Class: &class, Candidates: candidates, Error: "class is full"},
+		{Type: NoMatch, Time: now, User: "yogi@example.com"},
+	}
+}
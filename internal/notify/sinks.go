@@ -0,0 +1,318 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var defaultTimeout = 30 * time.Second
+
+// request is what a sink sends for an event
+type request struct {
+	contentType string
+	headers     map[string]string
+	body        []byte
+}
+
+// Webhook POSTs the event as JSON, with the rendered title and message added
+type Webhook struct {
+	URL      string
+	Headers  map[string]string
+	Renderer *Renderer
+	Client   *http.Client
+}
+
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	req, err := w.request(event)
+	if err != nil {
+		return err
+	}
+	return post(ctx, w.Client, w.URL, req)
+}
+
+func (w *Webhook) preview(event Event) (string, error) {
+	req, err := w.request(event)
+	if err != nil {
+		return "", err
+	}
+	return formatRequest(w.URL, req), nil
+}
+
+func (w *Webhook) request(event Event) (request, error) {
+	title, message := w.Renderer.Render(event)
+	payload, err := json.Marshal(struct {
+		Event
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	}{event, title, message})
+	if err != nil {
+		return request{}, fmt.Errorf("error marshaling event: %v", err)
+	}
+	return request{contentType: "application/json", headers: w.Headers, body: payload}, nil
+}
+
+// Slack posts to a Slack incoming webhook
+type Slack struct {
+	URL      string
+	Renderer *Renderer
+	Client   *http.Client
+}
+
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	req, err := s.request(event)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.Client, s.URL, req)
+}
+
+func (s *Slack) preview(event Event) (string, error) {
+	req, err := s.request(event)
+	if err != nil {
+		return "", err
+	}
+	return formatRequest(s.URL, req), nil
+}
+
+func (s *Slack) request(event Event) (request, error) {
+	title, message := s.Renderer.Render(event)
+	payload, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", title, message),
+	})
+	if err != nil {
+		return request{}, fmt.Errorf("error marshaling slack message: %v", err)
+	}
+	return request{contentType: "application/json", body: payload}, nil
+}
+
+// Discord posts to a Discord webhook
+type Discord struct {
+	URL      string
+	Renderer *Renderer
+	Client   *http.Client
+}
+
+func (d *Discord) Notify(ctx context.Context, event Event) error {
+	req, err := d.request(event)
+	if err != nil {
+		return err
+	}
+	return post(ctx, d.Client, d.URL, req)
+}
+
+func (d *Discord) preview(event Event) (string, error) {
+	req, err := d.request(event)
+	if err != nil {
+		return "", err
+	}
+	return formatRequest(d.URL, req), nil
+}
+
+func (d *Discord) request(event Event) (request, error) {
+	title, message := d.Renderer.Render(event)
+	payload, err := json.Marshal(map[string]string{
+		"content": fmt.Sprintf("**%s**\n%s", title, message),
+	})
+	if err != nil {
+		return request{}, fmt.Errorf("error marshaling discord message: %v", err)
+	}
+	return request{contentType: "application/json", body: payload}, nil
+}
+
+// Ntfy publishes a push notification to an ntfy topic URL, e.g.
+// https://ntfy.sh/my-topic
+type Ntfy struct {
+	URL      string
+	Token    string // Optional access token
+	Renderer *Renderer
+	Client   *http.Client
+}
+
+func (n *Ntfy) Notify(ctx context.Context, event Event) error {
+	req, err := n.request(event)
+	if err != nil {
+		return err
+	}
+	return post(ctx, n.Client, n.URL, req)
+}
+
+func (n *Ntfy) preview(event Event) (string, error) {
+	req, err := n.request(event)
+	if err != nil {
+		return "", err
+	}
+	return formatRequest(n.URL, req), nil
+}
+
+func (n *Ntfy) request(event Event) (request, error) {
+	title, message := n.Renderer.Render(event)
+	headers := map[string]string{
+		"Title": singleLine(title),
+		"Tags":  string(event.Type),
+	}
+	if event.Type == Failed {
+		headers["Priority"] = "high"
+	}
+	if n.Token != "" {
+		headers["Authorization"] = "Bearer " + n.Token
+	}
+	return request{contentType: "text/plain; charset=utf-8", headers: headers, body: []byte(message)}, nil
+}
+
+// Email sends the event through an SMTP server, upgrading to TLS when the
+// server offers STARTTLS
+type Email struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+	To       []string
+	Renderer *Renderer
+	Timeout  time.Duration // Deadline for the whole exchange, defaults to 30s
+}
+
+func (e *Email) Notify(ctx context.Context, event Event) error {
+	msg, err := e.message(event)
+	if err != nil {
+		return err
+	}
+
+	// Callers may pass a context that is never cancelled, so the exchange
+	// always gets a deadline of its own
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := e.send(ctx, msg); err != nil {
+		return fmt.Errorf("error sending email: %v", err)
+	}
+	slog.DebugContext(ctx, "Notification emailed", "recipients", len(e.To))
+	return nil
+}
+
+// send is smtp.SendMail over a connection bound to ctx, since net/smtp has no
+// context support of its own
+func (e *Email) send(ctx context.Context, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	host, _, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (e *Email) preview(event Event) (string, error) {
+	msg, err := e.message(event)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("SMTP %s\n\n%s", e.Addr, msg), nil
+}
+
+func (e *Email) message(event Event) ([]byte, error) {
+	title, message := e.Renderer.Render(event)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", singleLine(title)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(message, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+	return msg.Bytes(), nil
+}
+
+// singleLine joins the lines of a title, which goes into a header where a
+// line break would end the header or start a new one
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func post(ctx context.Context, client *http.Client, url string, r request) error {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(r.body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", r.contentType)
+	for key, value := range r.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error: %s received status code %d", req.URL.Host, resp.StatusCode)
+	}
+	slog.DebugContext(ctx, "Notification delivered", "host", req.URL.Host)
+	return nil
+}
@@ -0,0 +1,359 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// received is a request captured by a stand-in webhook server
+type received struct {
+	method string
+	header http.Header
+	body   string
+}
+
+func newWebhookServer(t *testing.T, status int) (string, <-chan received) {
+	t.Helper()
+	requests := make(chan received, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{method: r.Method, header: r.Header, body: string(body)}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, requests
+}
+
+func sampleEvent(t *testing.T, eventType EventType) Event {
+	t.Helper()
+	for _, event := range Samples(time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)) {
+		if event.Type == eventType {
+			return event
+		}
+	}
+	t.Fatalf("no sample %s event", eventType)
+	return Event{}
+}
+
+func TestHTTPSinks(t *testing.T) {
+	event := sampleEvent(t, Booked)
+
+	tests := []struct {
+		name  string
+		cfg   SinkConfig
+		check func(t *testing.T, r received)
+	}{
+		{
+			name: "webhook",
+			cfg:  SinkConfig{Type: "webhook", Headers: map[string]string{"X-Secret": "s3cret"}},
+			check: func(t *testing.T, r received) {
+				if r.header.Get("X-Secret") != "s3cret" || r.header.Get("Content-Type") != "application/json" {
+					t.Errorf("headers = %v", r.header)
+				}
+				var payload struct {
+					Type    EventType `json:"type"`
+					Title   string    `json:"title"`
+					Message string    `json:"message"`
+				}
+				if err := json.Unmarshal([]byte(r.body), &payload); err != nil {
+					t.Fatal(err)
+				}
+				if payload.Type != Booked || payload.Title != event.Title() || payload.Message != event.Message() {
+					t.Errorf("payload = %+v", payload)
+				}
+			},
+		},
+		{
+			name: "slack",
+			cfg:  SinkConfig{Type: "slack"},
+			check: func(t *testing.T, r received) {
+				var payload map[string]string
+				if err := json.Unmarshal([]byte(r.body), &payload); err != nil {
+					t.Fatal(err)
+				}
+				if want := "*" + event.Title() + "*\n" + event.Message(); payload["text"] != want {
+					t.Errorf("text = %q, want %q", payload["text"], want)
+				}
+			},
+		},
+		{
+			name: "discord",
+			cfg:  SinkConfig{Type: "discord"},
+			check: func(t *testing.T, r received) {
+				var payload map[string]string
+				if err := json.Unmarshal([]byte(r.body), &payload); err != nil {
+					t.Fatal(err)
+				}
+				if want := "**" + event.Title() + "**\n" + event.Message(); payload["content"] != want {
+					t.Errorf("content = %q, want %q", payload["content"], want)
+				}
+			},
+		},
+		{
+			name: "ntfy",
+			cfg:  SinkConfig{Type: "ntfy", Token: "tk_123"},
+			check: func(t *testing.T, r received) {
+				if r.header.Get("Title") != event.Title() || r.header.Get("Tags") != "booked" {
+					t.Errorf("headers = %v", r.header)
+				}
+				if r.header.Get("Authorization") != "Bearer tk_123" {
+					t.Errorf("Authorization = %q", r.header.Get("Authorization"))
+				}
+				if r.body != event.Message() {
+					t.Errorf("body = %q, want %q", r.body, event.Message())
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			url, requests := newWebhookServer(t, http.StatusOK)
+			test.cfg.URL = url
+			notifier, err := New([]SinkConfig{test.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := notifier.Notify(t.Context(), event); err != nil {
+				t.Fatalf("Notify: %v", err)
+			}
+			r := <-requests
+			if r.method != "POST" {
+				t.Errorf("method = %s", r.method)
+			}
+			test.check(t, r)
+		})
+	}
+}
+
+func TestHTTPSinkErrorStatus(t *testing.T) {
+	url, _ := newWebhookServer(t, http.StatusInternalServerError)
+	notifier, err := New([]SinkConfig{{Type: "slack", URL: url}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(t.Context(), sampleEvent(t, Failed)); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Notify = %v, want a 500 error", err)
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	url, requests := newWebhookServer(t, http.StatusOK)
+	notifier, err := New([]SinkConfig{{Type: "webhook", URL: url, Events: []EventType{Failed}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, eventType := range []EventType{Booked, Failed, NoMatch} {
+		if err := notifier.Notify(t.Context(), sampleEvent(t, eventType)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(requests) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(requests))
+	}
+	if r := <-requests; !strings.Contains(r.body, `"type":"failed"`) {
+		t.Errorf("sent %s", r.body)
+	}
+}
+
+func TestUnknownEventRejected(t *testing.T) {
+	_, err := New([]SinkConfig{{Type: "ntfy", URL: "https://ntfy.sh/topic", Events: []EventType{"booked", "cancelled"}}})
+	if err == nil || !strings.Contains(err.Error(), `unknown event "cancelled"`) {
+		t.Errorf("New = %v, want an unknown event error", err)
+	}
+}
+
+// smtpServer is a minimal SMTP stand-in on host that records the message it
+// is sent
+func smtpServer(t *testing.T, host string) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", host, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					messages <- data.String()
+					reply("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL"), strings.HasPrefix(command, "RCPT"):
+				reply("250 OK")
+			case strings.HasPrefix(command, "AUTH"):
+				reply("235 OK")
+			case command == "DATA":
+				inData = true
+				reply("354 Go ahead")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestEmail(t *testing.T) {
+	addr, messages := smtpServer(t, "127.0.0.1")
+	notifier, err := New([]SinkConfig{{Type: "email", SMTP: addr, From: "bot@example.com", To: []string{"me@example.com", "you@example.com"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := sampleEvent(t, Booked)
+	if err := notifier.Notify(t.Context(), event); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	msg := <-messages
+	for _, want := range []string{
+		"From: bot@example.com\r\n",
+		"To: me@example.com, you@example.com\r\n",
+		"Subject: " + event.Title() + "\r\n",
+		"\r\n\r\n" + event.Message() + "\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message is missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestEmailIPv6(t *testing.T) {
+	// Authentication checks the host name taken from the bracketed address
+	addr, messages := smtpServer(t, "::1")
+	email := &Email{Addr: addr, Username: "bot", Password: "secret", From: "bot@example.com", To: []string{"me@example.com"}}
+	if err := email.Notify(t.Context(), sampleEvent(t, Booked)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	<-messages
+}
+
+func TestEmailSubjectEncoding(t *testing.T) {
+	templates := map[string]Template{"default": {Title: "Booked {{.Class.CenterName}} ☀"}}
+	preview, err := Preview(SinkConfig{Type: "email", SMTP: "[::1]:25", From: "a@example.com", To: []string{"b@example.com"}, Templates: templates}, sampleEvent(t, Booked))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Subject: " + mime.QEncoding.Encode("utf-8", "Booked South Lamar ☀") + "\r\n"
+	if !strings.Contains(preview, want) || strings.Contains(preview, "☀\r\n") {
+		t.Errorf("subject is not encoded, want %q in:\n%s", want, preview)
+	}
+}
+
+func TestEmailTimeout(t *testing.T) {
+	// A server that accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	email := &Email{Addr: listener.Addr().String(), From: "bot@example.com", To: []string{"me@example.com"}, Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	err = email.Notify(t.Context(), sampleEvent(t, Failed))
+	if err == nil {
+		t.Fatal("Notify succeeded against a silent server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify took %v, want it bounded by the timeout", elapsed)
+	}
+}
+
+func TestPreviewMasksCredentials(t *testing.T) {
+	event := sampleEvent(t, Booked)
+	tests := []struct {
+		cfg    SinkConfig
+		secret string
+	}{
+		{SinkConfig{Type: "ntfy", URL: "https://ntfy.sh/topic", Token: "tk_secret"}, "tk_secret"},
+		{SinkConfig{Type: "webhook", URL: "https://example.com/hook", Headers: map[string]string{"X-Api-Key": "key_secret"}}, "key_secret"},
+	}
+	for _, test := range tests {
+		preview, err := Preview(test.cfg, event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(preview, test.secret) || !strings.Contains(preview, "[REDACTED]") {
+			t.Errorf("%s preview leaks or does not mask the credential:\n%s", test.cfg.Type, preview)
+		}
+	}
+
+	preview, err := Preview(SinkConfig{Type: "ntfy", URL: "https://ntfy.sh/topic"}, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(preview, "Title: "+event.Title()) {
+		t.Errorf("preview hides the title:\n%s", preview)
+	}
+}
+
+func TestTitleHeadersAreSingleLine(t *testing.T) {
+	templates := map[string]Template{"default": {Title: "Booked\n{{.Class.CenterName}}\r\nBcc: someone@example.com"}}
+	event := sampleEvent(t, Booked)
+
+	url, requests := newWebhookServer(t, http.StatusOK)
+	notifier, err := New([]SinkConfig{{Type: "ntfy", URL: url, Templates: templates}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(t.Context(), event); err != nil {
+		t.Fatal(err)
+	}
+	if title := (<-requests).header.Get("Title"); title != "Booked South Lamar Bcc: someone@example.com" {
+		t.Errorf("ntfy Title = %q", title)
+	}
+
+	preview, err := Preview(SinkConfig{Type: "email", SMTP: "localhost:25", From: "a@example.com", To: []string{"b@example.com"}, Templates: templates}, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(preview, "Subject: Booked South Lamar Bcc: someone@example.com\r\n") || strings.Contains(preview, "\r\nBcc:") {
+		t.Errorf("email headers were split:\n%s", preview)
+	}
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Template is the wording of a notification as text/template sources. The
+// template data is the Event, so templates can use .Class, .Reservation,
+// .Candidates, .Error and the default .Title and .Message.
+type Template struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+// defaultTemplateKey is the key of the template used for event types that
+// have none of their own
+const defaultTemplateKey = "default"
+
+var templateFuncs = template.FuncMap{
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"add":   func(a, b int) int { return a + b },
+}
+
+type compiledTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// Renderer produces the title and body of notifications
+type Renderer struct {
+	templates map[string]compiledTemplate
+}
+
+// NewRenderer compiles templates keyed by event type, or "default" for the
+// fallback. Event types without a template use the built-in wording.
+func NewRenderer(templates map[string]Template) (*Renderer, error) {
+	r := &Renderer{templates: map[string]compiledTemplate{}}
+	for key, tmpl := range templates {
+		var compiled compiledTemplate
+		var err error
+		if tmpl.Title != "" {
+			if compiled.title, err = template.New(key + " title").Funcs(templateFuncs).Parse(tmpl.Title); err != nil {
+				return nil, fmt.Errorf("invalid %s title template: %v", key, err)
+			}
+		}
+		if tmpl.Body != "" {
+			if compiled.body, err = template.New(key + " body").Funcs(templateFuncs).Parse(tmpl.Body); err != nil {
+				return nil, fmt.Errorf("invalid %s body template: %v", key, err)
+			}
+		}
+		r.templates[key] = compiled
+	}
+	return r, nil
+}
+
+// Render returns the title and body for an event. A template that fails to
+// execute, e.g. on a field missing from this event type, is logged and the
+// built-in wording used instead, so that the notification still goes out.
+func (r *Renderer) Render(event Event) (title, body string) {
+	title, body = event.Title(), event.Message()
+	if r == nil {
+		return title, body
+	}
+
+	compiled, ok := r.templates[string(event.Type)]
+	if !ok {
+		compiled = r.templates[defaultTemplateKey]
+	}
+
+	if compiled.title != nil {
+		if rendered, err := execute(compiled.title, event); err != nil {
+			slog.Error("Error rendering notification, using the default title", "event", event.Type, "error", err)
+		} else {
+			title = rendered
+		}
+	}
+	if compiled.body != nil {
+		if rendered, err := execute(compiled.body, event); err != nil {
+			slog.Error("Error rendering notification, using the default message", "event", event.Type, "error", err)
+		} else {
+			body = rendered
+		}
+	}
+	return title, body
+}
+
+func execute(tmpl *template.Template, event Event) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("error rendering %s template: %v", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
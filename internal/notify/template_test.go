@@ -0,0 +1,36 @@
+package notify
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	renderer, err := NewRenderer(map[string]Template{
+		"booked":  {Title: "{{.Class.CenterName}}", Body: "{{upper .Class.ClassCategoryName}} {{date \"Jan 2\" .Class.StartTime}}"},
+		"default": {Title: "Heads up: {{.Title}}"},
+		// .Class is nil for no_match, so this fails to execute
+		"no_match": {Title: "{{.Class.CenterName}}", Body: "Nothing for {{.User}}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	booked := sampleEvent(t, Booked)
+	tests := []struct {
+		eventType EventType
+		title     string
+		body      string
+	}{
+		{Booked, "South Lamar", "YOGA SCULPT " + booked.Class.StartTime.Format("Jan 2")},
+		{Failed, "Heads up: CorePower booking failed", ""},
+		{NoMatch, "No CorePower class matched", "Nothing for yogi@example.com"},
+	}
+	for _, test := range tests {
+		event := sampleEvent(t, test.eventType)
+		title, body := renderer.Render(event)
+		if test.body == "" {
+			test.body = event.Message()
+		}
+		if title != test.title || body != test.body {
+			t.Errorf("%s: Render = %q, %q, want %q, %q", test.eventType, title, body, test.title, test.body)
+		}
+	}
+}
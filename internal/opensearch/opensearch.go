@@ -2,15 +2,29 @@ package opensearch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
+
+	"github.com/eshaanm25/corepower/internal/metrics"
+	"github.com/eshaanm25/corepower/internal/retry"
 )
 
 var (
 	openSearchEndpoint = "https://9a6fd5b868a24c039c29be8d490b3766.ent-search.us-west-1.aws.cloud.es.io"
+	defaultTimeout     = 30 * time.Second
+)
+
+const (
+	// App Search caps page size at 1000 and only serves the first 100 pages
+	maxPageSize = 1000
+	maxPages    = 100
 )
 
 type headerTransport struct {
@@ -28,28 +42,10 @@ func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 type Client struct {
 	SearchClient *http.Client
 	Endpoint     *url.URL
-}
-
-type SearchRequest struct {
-	Query string `json:"query"`
-	Page  struct {
-		Current int `json:"current"`
-		Size    int `json:"size"`
-	} `json:"page"`
-	Sort struct {
-		StartTime string `json:"start_time"`
-	} `json:"sort"`
-	Filters struct {
-		Any []struct {
-			StartTimeUtc struct {
-				From time.Time `json:"from"`
-				To   time.Time `json:"to"`
-			} `json:"start_time_utc"`
-		} `json:"any"`
-		All []struct {
-			CenterID []string `json:"center.id"`
-		} `json:"all"`
-	} `json:"filters"`
+	Timeout      time.Duration // Deadline for a single request, defaults to 30s
+	Retry        retry.Policy
+	PageSize     int // Results per page, defaults to the App Search maximum
+	Concurrency  int // Pages fetched in parallel after the first, defaults to 1
 }
 
 type SearchResponse struct {
@@ -69,154 +65,163 @@ type SearchResponse struct {
 		RequestID string        `json:"request_id"`
 		Warnings  []interface{} `json:"warnings"`
 	} `json:"meta"`
-	Results []struct {
-		Meta struct {
-			Engine string      `json:"engine"`
-			ID     string      `json:"id"`
-			Score  interface{} `json:"score"`
-		} `json:"_meta"`
-		AvailableSlots struct {
-			Raw float32 `json:"raw"`
-		} `json:"available_slots"`
-		CanBook struct {
-			Raw string `json:"raw"`
-		} `json:"can_book"`
-		CanBookStatus struct {
-			Raw interface{} `json:"raw"`
-		} `json:"can_book_status"`
-		Capacity struct {
-			Raw float32 `json:"raw"`
-		} `json:"capacity"`
-		CenterID struct {
-			Raw string `json:"raw"`
-		} `json:"center.id"`
-		CenterLocationLatitude struct {
-			Raw float64 `json:"raw"`
-		} `json:"center.location.latitude"`
-		CenterLocationLongitude struct {
-			Raw float64 `json:"raw"`
-		} `json:"center.location.longitude"`
-		CenterName struct {
-			Raw string `json:"raw"`
-		} `json:"center.name"`
-		ClassCategoryID struct {
-			Raw float32 `json:"raw"`
-		} `json:"class.category.id"`
-		ClassCategoryName struct {
-			Raw string `json:"raw"`
-		} `json:"class.category.name"`
-		ClassCategoryParentID struct {
-			Raw float32 `json:"raw"`
-		} `json:"class.category.parent_id"`
-		ClassDescription struct {
-			Raw interface{} `json:"raw"`
-		} `json:"class.description"`
-		ClassID struct {
-			Raw float32 `json:"raw"`
-		} `json:"class.id"`
-		ClassShowInCatalog struct {
-			Raw float32 `json:"raw"`
-		} `json:"class.show_in_catalog"`
-		ClassTags []struct {
-			Description struct {
-				Raw string `json:"raw"`
-			} `json:"description"`
-			ID struct {
-				Raw string `json:"raw"`
-			} `json:"id"`
-			Name struct {
-				Raw string `json:"raw"`
-			} `json:"name"`
-		} `json:"class.tags"`
-		ClassType struct {
-			Raw float32 `json:"raw"`
-		} `json:"class.type"`
+	Results []Class `json:"results"`
+}
+
+// Class is a single scheduled session returned by the search engine
+type Class struct {
+	Meta struct {
+		Engine string      `json:"engine"`
+		ID     string      `json:"id"`
+		Score  interface{} `json:"score"`
+	} `json:"_meta"`
+	AvailableSlots struct {
+		Raw float32 `json:"raw"`
+	} `json:"available_slots"`
+	CanBook struct {
+		Raw string `json:"raw"`
+	} `json:"can_book"`
+	CanBookStatus struct {
+		Raw interface{} `json:"raw"`
+	} `json:"can_book_status"`
+	Capacity struct {
+		Raw float32 `json:"raw"`
+	} `json:"capacity"`
+	CenterID struct {
+		Raw string `json:"raw"`
+	} `json:"center.id"`
+	CenterLocationLatitude struct {
+		Raw float64 `json:"raw"`
+	} `json:"center.location.latitude"`
+	CenterLocationLongitude struct {
+		Raw float64 `json:"raw"`
+	} `json:"center.location.longitude"`
+	CenterName struct {
+		Raw string `json:"raw"`
+	} `json:"center.name"`
+	ClassCategoryID struct {
+		Raw float32 `json:"raw"`
+	} `json:"class.category.id"`
+	ClassCategoryName struct {
+		Raw string `json:"raw"`
+	} `json:"class.category.name"`
+	ClassCategoryParentID struct {
+		Raw float32 `json:"raw"`
+	} `json:"class.category.parent_id"`
+	ClassDescription struct {
+		Raw interface{} `json:"raw"`
+	} `json:"class.description"`
+	ClassID struct {
+		Raw float32 `json:"raw"`
+	} `json:"class.id"`
+	ClassShowInCatalog struct {
+		Raw float32 `json:"raw"`
+	} `json:"class.show_in_catalog"`
+	ClassTags []struct {
 		Description struct {
 			Raw string `json:"raw"`
 		} `json:"description"`
-		Duration struct {
-			Raw float32 `json:"raw"`
-		} `json:"duration"`
-		EndTime struct {
-			Raw time.Time `json:"raw"`
-		} `json:"end_time"`
-		EndTimeUtc struct {
-			Raw time.Time `json:"raw"`
-		} `json:"end_time_utc"`
-		GuestPassID struct {
-			Raw interface{} `json:"raw"`
-		} `json:"guest_pass_id"`
-		HasDescription struct {
-			Raw string `json:"raw"`
-		} `json:"hasDescription"`
 		ID struct {
 			Raw string `json:"raw"`
 		} `json:"id"`
-		InstructorID struct {
-			Raw string `json:"raw"`
-		} `json:"instructor_id"`
-		Instructors struct {
-			Description struct {
-				Raw interface{} `json:"raw"`
-			} `json:"description"`
-			ID struct {
-				Raw string `json:"raw"`
-			} `json:"id"`
-			ImageURL struct {
-				Raw string `json:"raw"`
-			} `json:"imageUrl"`
-			Name struct {
-				Raw string `json:"raw"`
-			} `json:"name"`
-		} `json:"instructors"`
-		IsFreeSession struct {
+		Name struct {
 			Raw string `json:"raw"`
-		} `json:"is_free_session"`
-		IsInstructorSubstituted struct {
+		} `json:"name"`
+	} `json:"class.tags"`
+	ClassType struct {
+		Raw float32 `json:"raw"`
+	} `json:"class.type"`
+	Description struct {
+		Raw string `json:"raw"`
+	} `json:"description"`
+	Duration struct {
+		Raw float32 `json:"raw"`
+	} `json:"duration"`
+	EndTime struct {
+		Raw time.Time `json:"raw"`
+	} `json:"end_time"`
+	EndTimeUtc struct {
+		Raw time.Time `json:"raw"`
+	} `json:"end_time_utc"`
+	GuestPassID struct {
+		Raw interface{} `json:"raw"`
+	} `json:"guest_pass_id"`
+	HasDescription struct {
+		Raw string `json:"raw"`
+	} `json:"hasDescription"`
+	ID struct {
+		Raw string `json:"raw"`
+	} `json:"id"`
+	InstructorID struct {
+		Raw string `json:"raw"`
+	} `json:"instructor_id"`
+	Instructors struct {
+		Description struct {
+			Raw interface{} `json:"raw"`
+		} `json:"description"`
+		ID struct {
 			Raw string `json:"raw"`
-		} `json:"is_instructor_substituted"`
-		IsVirtualClass struct {
+		} `json:"id"`
+		ImageURL struct {
 			Raw string `json:"raw"`
-		} `json:"is_virtual_class"`
-		LastUpdateEventDate struct {
-			Raw float64 `json:"raw"`
-		} `json:"lastUpdateEventDate"`
+		} `json:"imageUrl"`
 		Name struct {
 			Raw string `json:"raw"`
 		} `json:"name"`
-		Occupancy struct {
-			Raw float32 `json:"raw"`
-		} `json:"occupancy"`
-		Price struct {
-			Raw float32 `json:"raw"`
-		} `json:"price"`
-		RoomID struct {
-			Raw interface{} `json:"raw"`
-		} `json:"room_id"`
-		SessionGUID struct {
-			Raw string `json:"raw"`
-		} `json:"session_guid"`
-		SessionID struct {
-			Raw float32 `json:"raw"`
-		} `json:"session_id"`
-		StartTime struct {
-			Raw time.Time `json:"raw"`
-		} `json:"start_time"`
-		StartTimeUtc struct {
-			Raw time.Time `json:"raw"`
-		} `json:"start_time_utc"`
-		Status struct {
-			Raw float32 `json:"raw"`
-		} `json:"status"`
-		WaitListedCount struct {
-			Raw float32 `json:"raw"`
-		} `json:"wait_listed_count"`
-	} `json:"results"`
+	} `json:"instructors"`
+	IsFreeSession struct {
+		Raw string `json:"raw"`
+	} `json:"is_free_session"`
+	IsInstructorSubstituted struct {
+		Raw string `json:"raw"`
+	} `json:"is_instructor_substituted"`
+	IsVirtualClass struct {
+		Raw string `json:"raw"`
+	} `json:"is_virtual_class"`
+	LastUpdateEventDate struct {
+		Raw float64 `json:"raw"`
+	} `json:"lastUpdateEventDate"`
+	Name struct {
+		Raw string `json:"raw"`
+	} `json:"name"`
+	Occupancy struct {
+		Raw float32 `json:"raw"`
+	} `json:"occupancy"`
+	Price struct {
+		Raw float32 `json:"raw"`
+	} `json:"price"`
+	RoomID struct {
+		Raw interface{} `json:"raw"`
+	} `json:"room_id"`
+	SessionGUID struct {
+		Raw string `json:"raw"`
+	} `json:"session_guid"`
+	SessionID struct {
+		Raw int64 `json:"raw"`
+	} `json:"session_id"`
+	StartTime struct {
+		Raw time.Time `json:"raw"`
+	} `json:"start_time"`
+	StartTimeUtc struct {
+		Raw time.Time `json:"raw"`
+	} `json:"start_time_utc"`
+	Status struct {
+		Raw float32 `json:"raw"`
+	} `json:"status"`
+	WaitListedCount struct {
+		Raw float32 `json:"raw"`
+	} `json:"wait_listed_count"`
 }
 
 func (c *Client) Initialize() {
 	c.Endpoint, _ = url.Parse(openSearchEndpoint)
-	c.SearchClient = &http.Client{}
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+	c.SearchClient = &http.Client{Timeout: c.Timeout}
+	if c.Retry.MaxAttempts == 0 {
+		c.Retry = retry.DefaultPolicy
+	}
 
 	// Set default headers for all requests
 	c.SearchClient.Transport = &headerTransport{
@@ -227,68 +232,166 @@ func (c *Client) Initialize() {
 	}
 }
 
-func (c *Client) Search(startTime time.Time, endTime time.Time, centerIds []string) (*SearchResponse, error) {
+// Search returns every class scheduled between startTime and endTime at the
+// given centers, fetching as many pages as needed
+func (c *Client) Search(ctx context.Context, startTime time.Time, endTime time.Time, centerIds []string) (*SearchResponse, error) {
+	return c.Find(ctx, NewQuery().
+		Where(Centers(centerIds...)).
+		WhereAny(StartingBetween(startTime, endTime)).
+		SortBy(FieldStartTime, "asc"))
+}
 
-	payload := SearchRequest{
-		Query: "",
-		Page: struct {
-			Current int `json:"current"`
-			Size    int `json:"size"`
-		}{
-			Current: 1,
-			Size:    999,
-		},
-		Sort: struct {
-			StartTime string `json:"start_time"`
-		}{
-			StartTime: "asc",
-		},
-		Filters: struct {
-			Any []struct {
-				StartTimeUtc struct {
-					From time.Time `json:"from"`
-					To   time.Time `json:"to"`
-				} `json:"start_time_utc"`
-			} `json:"any"`
-			All []struct {
-				CenterID []string `json:"center.id"`
-			} `json:"all"`
-		}{
-			Any: []struct {
-				StartTimeUtc struct {
-					From time.Time `json:"from"`
-					To   time.Time `json:"to"`
-				} `json:"start_time_utc"`
-			}{
-				{
-					StartTimeUtc: struct {
-						From time.Time `json:"from"`
-						To   time.Time `json:"to"`
-					}{
-						From: startTime,
-						To:   endTime,
-					},
-				},
-			},
-			All: []struct {
-				CenterID []string `json:"center.id"`
-			}{
-				{
-					CenterID: centerIds,
-				},
-			},
-		},
+// Find returns every class matching the query, fetching as many pages as
+// needed
+func (c *Client) Find(ctx context.Context, query *Query) (*SearchResponse, error) {
+	start := time.Now()
+	res, err := c.searchAll(ctx, query)
+	metrics.SearchDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.SearchErrors.Inc()
+		return nil, err
 	}
+	metrics.SearchResults.Observe(float64(len(res.Results)))
+	slog.DebugContext(ctx, "Search complete", "results", len(res.Results), "duration", time.Since(start))
+	return res, nil
+}
+
+// Classes streams the results of a query one page at a time, so callers can
+// stop early without holding every page in memory
+func (c *Client) Classes(ctx context.Context, query *Query) iter.Seq2[Class, error] {
+	return c.classes(ctx, query)
+}
 
-	payloadBytes, err := json.Marshal(payload)
+// searchAll fetches the first page to learn the page count, then the rest,
+// concurrently when Concurrency allows it
+func (c *Client) searchAll(ctx context.Context, query *Query) (*SearchResponse, error) {
+	first, err := c.page(ctx, query, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := first.Meta.Page.TotalPages
+	if totalPages > maxPages {
+		return nil, fmt.Errorf("search matched %d results, more than the %d that can be paged through; narrow the search",
+			first.Meta.Page.TotalResults, maxPages*c.pageSize())
+	}
+	if totalPages <= 1 {
+		return first, nil
+	}
+
+	pages := make([]*SearchResponse, totalPages)
+	pages[0] = first
+
+	// At most Concurrency pages are in flight, and the first failure stops
+	// the rest
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, max(c.Concurrency, 1))
+	for n := 2; n <= totalPages; n++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			page, err := c.page(ctx, query, n)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			pages[n-1] = page
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	searchResponse := first
+	for _, page := range pages[1:] {
+		searchResponse.Results = append(searchResponse.Results, page.Results...)
+	}
+	searchResponse.Meta.Page.Current = 1
+	searchResponse.Meta.Page.Size = len(searchResponse.Results)
+
+	return searchResponse, nil
+}
+
+func (c *Client) classes(ctx context.Context, query *Query) iter.Seq2[Class, error] {
+	return func(yield func(Class, error) bool) {
+		for n := 1; ; n++ {
+			page, err := c.page(ctx, query, n)
+			if err != nil {
+				yield(Class{}, err)
+				return
+			}
+
+			for _, class := range page.Results {
+				if !yield(class, nil) {
+					return
+				}
+			}
+
+			if n >= min(page.Meta.Page.TotalPages, maxPages) {
+				return
+			}
+		}
+	}
+}
+
+// page fetches a single page of results, retrying transient failures
+func (c *Client) page(ctx context.Context, query *Query, n int) (*SearchResponse, error) {
+	payloadBytes, err := json.Marshal(query.request(n, c.pageSize()))
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling payload: %v", err)
 	}
 
-	baseURL := c.Endpoint
+	var searchResponse *SearchResponse
+	err = c.Retry.Do(ctx, func(ctx context.Context) error {
+		searchResponse, err = c.search(ctx, payloadBytes)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching page %d: %w", n, err)
+	}
+	slog.DebugContext(ctx, "Fetched search page", "page", n, "total_pages", searchResponse.Meta.Page.TotalPages, "results", len(searchResponse.Results))
+
+	return searchResponse, nil
+}
+
+func (c *Client) pageSize() int {
+	if c.PageSize <= 0 || c.PageSize > maxPageSize {
+		return maxPageSize
+	}
+	return c.PageSize
+}
+
+// search sends a single search request
+func (c *Client) search(ctx context.Context, payload []byte) (*SearchResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	baseURL := *c.Endpoint
 	baseURL.Path = "/api/as/v1/engines/schedule-search-prod/search"
 
-	req, err := http.NewRequest("POST", baseURL.String(), bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL.String(), bytes.NewReader(payload))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
@@ -297,12 +400,12 @@ func (c *Client) Search(startTime time.Time, endTime time.Time, centerIds []stri
 
 	resp, err := c.SearchClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
+		return nil, retry.Retryable(fmt.Errorf("error making request: %v", err), 0)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error: received status code %d", resp.StatusCode)
+		return nil, c.Retry.FromResponse(resp, fmt.Errorf("error: received status code %d", resp.StatusCode))
 	}
 
 	var searchResponse SearchResponse
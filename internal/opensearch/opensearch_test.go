@@ -0,0 +1,234 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/retry"
+)
+
+// fakeEngine serves total results, numbered from 0, a page at a time
+type fakeEngine struct {
+	total    int
+	failPage int           // Page answered with a 400 right away, 0 for none
+	delay    time.Duration // How long each page takes
+
+	mu          sync.Mutex
+	pages       []int // Pages requested, in order
+	inFlight    int
+	maxInFlight int
+}
+
+func (f *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req searchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.pages = append(f.pages, req.Page.Current)
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	if req.Page.Current == f.failPage {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	select {
+	case <-time.After(f.delay):
+	case <-r.Context().Done():
+		return
+	}
+
+	var res SearchResponse
+	res.Meta.Page.Current = req.Page.Current
+	res.Meta.Page.Size = req.Page.Size
+	res.Meta.Page.TotalResults = f.total
+	res.Meta.Page.TotalPages = (f.total + req.Page.Size - 1) / req.Page.Size
+	for i := (req.Page.Current - 1) * req.Page.Size; i < min(req.Page.Current*req.Page.Size, f.total); i++ {
+		var class Class
+		class.SessionGUID.Raw = fmt.Sprint(i)
+		res.Results = append(res.Results, class)
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func (f *fakeEngine) requested() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.pages)
+}
+
+func newTestClient(t *testing.T, engine *fakeEngine, pageSize, concurrency int) *Client {
+	t.Helper()
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	endpoint, _ := url.Parse(server.URL)
+	return &Client{
+		SearchClient: server.Client(),
+		Endpoint:     endpoint,
+		Timeout:      5 * time.Second,
+		Retry:        retry.Policy{MaxAttempts: 1},
+		PageSize:     pageSize,
+		Concurrency:  concurrency,
+	}
+}
+
+func guids(classes []Class) []string {
+	var guids []string
+	for _, class := range classes {
+		guids = append(guids, class.SessionGUID.Raw)
+	}
+	return guids
+}
+
+func numbered(n int) []string {
+	var want []string
+	for i := range n {
+		want = append(want, fmt.Sprint(i))
+	}
+	return want
+}
+
+func TestFindPages(t *testing.T) {
+	tests := []struct {
+		total, pageSize, concurrency int
+	}{
+		{0, 10, 1},
+		{7, 10, 1},
+		{10, 10, 1},
+		{25, 10, 1},
+		{95, 10, 3},
+	}
+	for _, test := range tests {
+		engine := &fakeEngine{total: test.total, delay: 5 * time.Millisecond}
+		client := newTestClient(t, engine, test.pageSize, test.concurrency)
+
+		res, err := client.Find(context.Background(), NewQuery())
+		if err != nil {
+			t.Errorf("%d results: %v", test.total, err)
+			continue
+		}
+		if got, want := guids(res.Results), numbered(test.total); !slices.Equal(got, want) {
+			t.Errorf("%d results: got %v, want %v", test.total, got, want)
+		}
+		// Merged pages are reported as a single one
+		if test.total > test.pageSize && res.Meta.Page.Size != test.total {
+			t.Errorf("%d results: got page size %d", test.total, res.Meta.Page.Size)
+		}
+		if pages := max((test.total+test.pageSize-1)/test.pageSize, 1); len(engine.requested()) != pages {
+			t.Errorf("%d results: requested pages %v, want %d", test.total, engine.requested(), pages)
+		}
+		engine.mu.Lock()
+		if engine.maxInFlight > test.concurrency {
+			t.Errorf("%d results: %d pages in flight, want at most %d", test.total, engine.maxInFlight, test.concurrency)
+		}
+		engine.mu.Unlock()
+	}
+}
+
+func TestFindStopsOnError(t *testing.T) {
+	engine := &fakeEngine{total: 100, failPage: 3}
+	client := newTestClient(t, engine, 10, 1)
+
+	_, err := client.Find(context.Background(), NewQuery())
+	if err == nil || !strings.Contains(err.Error(), "page 3") {
+		t.Fatalf("got error %v, want one for page 3", err)
+	}
+	if got := engine.requested(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("requested pages %v after page 3 failed", got)
+	}
+}
+
+func TestFindCancelsOnError(t *testing.T) {
+	engine := &fakeEngine{total: 100, failPage: 2, delay: 200 * time.Millisecond}
+	client := newTestClient(t, engine, 10, 3)
+
+	start := time.Now()
+	_, err := client.Find(context.Background(), NewQuery())
+	if err == nil || !strings.Contains(err.Error(), "page 2") {
+		t.Fatalf("got error %v, want the one for page 2", err)
+	}
+	// The first page takes the full delay, the pages in flight with page 2
+	// are cancelled rather than waited for
+	if elapsed := time.Since(start); elapsed > 390*time.Millisecond {
+		t.Errorf("took %v, pages in flight were not cancelled", elapsed)
+	}
+	if got := engine.requested(); len(got) > 4 {
+		t.Errorf("requested pages %v after page 2 failed", got)
+	}
+}
+
+func TestFindTooManyPages(t *testing.T) {
+	engine := &fakeEngine{total: (maxPages + 1) * 10}
+	client := newTestClient(t, engine, 10, 1)
+
+	if _, err := client.Find(context.Background(), NewQuery()); err == nil || !strings.Contains(err.Error(), "narrow the search") {
+		t.Errorf("got error %v", err)
+	}
+	if got := engine.requested(); len(got) != 1 {
+		t.Errorf("requested pages %v, want only the first", got)
+	}
+}
+
+func TestClasses(t *testing.T) {
+	engine := &fakeEngine{total: 25}
+	client := newTestClient(t, engine, 10, 1)
+
+	var all []Class
+	for class, err := range client.Classes(context.Background(), NewQuery()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, class)
+	}
+	if got, want := guids(all), numbered(25); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Stopping early fetches no more pages
+	engine = &fakeEngine{total: 25}
+	client = newTestClient(t, engine, 10, 1)
+	for class, err := range client.Classes(context.Background(), NewQuery()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if class.SessionGUID.Raw == "12" {
+			break
+		}
+	}
+	if got := engine.requested(); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("requested pages %v, want [1 2]", got)
+	}
+
+	// A failing page ends the iteration with its error
+	engine = &fakeEngine{total: 25, failPage: 2}
+	client = newTestClient(t, engine, 10, 1)
+	var seen int
+	var iterErr error
+	for _, err := range client.Classes(context.Background(), NewQuery()) {
+		if err != nil {
+			iterErr = err
+			continue
+		}
+		seen++
+	}
+	if iterErr == nil || seen != 10 {
+		t.Errorf("got %d classes and error %v, want 10 and an error", seen, iterErr)
+	}
+}
@@ -0,0 +1,287 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Field names understood by the schedule search engine
+const (
+	FieldAvailableSlots          = "available_slots"
+	FieldCanBook                 = "can_book"
+	FieldCapacity                = "capacity"
+	FieldCenterID                = "center.id"
+	FieldCenterName              = "center.name"
+	FieldCenterLat               = "center.location.latitude"
+	FieldCenterLong              = "center.location.longitude"
+	FieldCategoryName            = "class.category.name"
+	FieldClassType               = "class.type"
+	FieldEndTimeUtc              = "end_time_utc"
+	FieldInstructorID            = "instructor_id"
+	FieldInstructors             = "instructors"
+	FieldIsFreeSession           = "is_free_session"
+	FieldIsInstructorSubstituted = "is_instructor_substituted"
+	FieldIsVirtual               = "is_virtual_class"
+	FieldOccupancy               = "occupancy"
+	FieldPrice                   = "price"
+	FieldSessionGUID             = "session_guid"
+	FieldSessionID               = "session_id"
+	FieldStartTime               = "start_time"
+	FieldStartTimeUtc            = "start_time_utc"
+	FieldStatus                  = "status"
+	FieldWaitListedCount         = "wait_listed_count"
+)
+
+// Filter is a single App Search filter clause. Filters nest through All, Any
+// and None. A filter whose JSON is nil places no constraint and is left out.
+type Filter interface {
+	filterJSON() any
+}
+
+type valueFilter struct {
+	field  string
+	values []any
+}
+
+func (f valueFilter) filterJSON() any {
+	if len(f.values) == 0 {
+		return nil
+	}
+	return map[string]any{f.field: f.values}
+}
+
+type rangeFilter struct {
+	field    string
+	from, to any
+}
+
+func (f rangeFilter) filterJSON() any {
+	bounds := map[string]any{}
+	if f.from != nil {
+		bounds["from"] = f.from
+	}
+	if f.to != nil {
+		bounds["to"] = f.to
+	}
+	return map[string]any{f.field: bounds}
+}
+
+type combinator struct {
+	kind    string
+	filters []Filter
+}
+
+func (f combinator) filterJSON() any {
+	clauses := filterClauses(f.filters)
+	if len(clauses) == 0 {
+		return nil
+	}
+	return map[string]any{f.kind: clauses}
+}
+
+// filterClauses renders filters, leaving out those without constraints
+func filterClauses(filters []Filter) []any {
+	var clauses []any
+	for _, filter := range filters {
+		if clause := filter.filterJSON(); clause != nil {
+			clauses = append(clauses, clause)
+		}
+	}
+	return clauses
+}
+
+// Values matches documents whose field equals any of the values. Without
+// values it matches everything.
+func Values[T any](field string, values ...T) Filter {
+	anyValues := make([]any, 0, len(values))
+	for _, value := range values {
+		anyValues = append(anyValues, value)
+	}
+	return valueFilter{field: field, values: anyValues}
+}
+
+// Range matches documents whose field is within [from, to). A nil bound is
+// left open.
+func Range(field string, from, to any) Filter {
+	return rangeFilter{field: field, from: from, to: to}
+}
+
+// All matches when every filter matches
+func All(filters ...Filter) Filter {
+	return combinator{kind: "all", filters: filters}
+}
+
+// Any matches when at least one filter matches
+func Any(filters ...Filter) Filter {
+	return combinator{kind: "any", filters: filters}
+}
+
+// None matches when no filter matches
+func None(filters ...Filter) Filter {
+	return combinator{kind: "none", filters: filters}
+}
+
+// Centers matches classes at any of the given center IDs, or at any center
+// when none are given
+func Centers(ids ...string) Filter {
+	return Values(FieldCenterID, ids...)
+}
+
+// Categories matches classes in any of the given categories, e.g. "Yoga Sculpt"
+func Categories(names ...string) Filter {
+	return Values(FieldCategoryName, names...)
+}
+
+// Instructors matches classes taught by any of the given instructor IDs
+func Instructors(ids ...string) Filter {
+	return Values(FieldInstructorID, ids...)
+}
+
+// ClassTypes matches classes of any of the given class types
+func ClassTypes(types ...int) Filter {
+	return Values(FieldClassType, types...)
+}
+
+// Statuses matches classes in any of the given statuses
+func Statuses(statuses ...int) Filter {
+	return Values(FieldStatus, statuses...)
+}
+
+// Virtual matches virtual classes, or in-person classes when false
+func Virtual(virtual bool) Filter {
+	return Values(FieldIsVirtual, strconv.FormatBool(virtual))
+}
+
+// FreeSession matches free sessions, or paid sessions when false
+func FreeSession(free bool) Filter {
+	return Values(FieldIsFreeSession, strconv.FormatBool(free))
+}
+
+// CanBook matches classes that can (or cannot) currently be booked
+func CanBook(canBook bool) Filter {
+	return Values(FieldCanBook, strconv.FormatBool(canBook))
+}
+
+// PriceBetween matches classes priced within [from, to)
+func PriceBetween(from, to float64) Filter {
+	return Range(FieldPrice, from, to)
+}
+
+// StartingBetween matches classes starting within [from, to)
+func StartingBetween(from, to time.Time) Filter {
+	return Range(FieldStartTimeUtc, from.UTC(), to.UTC())
+}
+
+// Query is a search against the schedule engine, built up with chained calls:
+//
+//	opensearch.NewQuery().
+//		Where(opensearch.Centers(ids...), opensearch.CanBook(true)).
+//		SortBy(opensearch.FieldStartTime, "asc")
+type Query struct {
+	text   string
+	all    []Filter
+	any    []Filter
+	none   []Filter
+	sort   []map[string]string
+	fields []string
+	group  string
+}
+
+// NewQuery returns an empty query matching every class
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// Text sets the full-text query
+func (q *Query) Text(text string) *Query {
+	q.text = text
+	return q
+}
+
+// Where adds filters that must all match
+func (q *Query) Where(filters ...Filter) *Query {
+	q.all = append(q.all, filters...)
+	return q
+}
+
+// WhereAny adds filters of which at least one must match
+func (q *Query) WhereAny(filters ...Filter) *Query {
+	q.any = append(q.any, filters...)
+	return q
+}
+
+// WhereNone adds filters that must not match
+func (q *Query) WhereNone(filters ...Filter) *Query {
+	q.none = append(q.none, filters...)
+	return q
+}
+
+// SortBy adds a sort field, direction is "asc" or "desc"
+func (q *Query) SortBy(field, direction string) *Query {
+	q.sort = append(q.sort, map[string]string{field: direction})
+	return q
+}
+
+// Fields limits the returned document fields to the given ones
+func (q *Query) Fields(fields ...string) *Query {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+// GroupBy collapses results sharing a value of field into one result each
+func (q *Query) GroupBy(field string) *Query {
+	q.group = field
+	return q
+}
+
+type searchRequest struct {
+	Query string `json:"query"`
+	Page  struct {
+		Current int `json:"current"`
+		Size    int `json:"size"`
+	} `json:"page"`
+	Sort         []map[string]string `json:"sort,omitempty"`
+	Filters      map[string][]any    `json:"filters,omitempty"`
+	ResultFields map[string]any      `json:"result_fields,omitempty"`
+	Group        map[string]string   `json:"group,omitempty"`
+}
+
+// request builds the App Search request body for one page
+func (q *Query) request(page, size int) searchRequest {
+	req := searchRequest{
+		Query: q.text,
+		Sort:  q.sort,
+	}
+	req.Page.Current = page
+	req.Page.Size = size
+
+	for kind, filters := range map[string][]Filter{"all": q.all, "any": q.any, "none": q.none} {
+		clauses := filterClauses(filters)
+		if len(clauses) == 0 {
+			continue
+		}
+		if req.Filters == nil {
+			req.Filters = map[string][]any{}
+		}
+		req.Filters[kind] = clauses
+	}
+
+	if len(q.fields) > 0 {
+		req.ResultFields = map[string]any{}
+		for _, field := range q.fields {
+			req.ResultFields[field] = map[string]any{"raw": struct{}{}}
+		}
+	}
+
+	if q.group != "" {
+		req.Group = map[string]string{"field": q.group}
+	}
+
+	return req
+}
+
+// MarshalJSON renders the first page of the query, mostly useful for debugging
+func (q *Query) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.request(1, maxPageSize))
+}
@@ -0,0 +1,91 @@
+package opensearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestQueryJSON(t *testing.T) {
+	from := time.Date(2024, 3, 5, 6, 0, 0, 0, time.FixedZone("CST", -6*60*60))
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{
+			"empty",
+			NewQuery(),
+			`{"query":"","page":{"current":1,"size":1000}}`,
+		},
+		{
+			"no centers",
+			NewQuery().
+				Where(Centers(), CanBook(true)).
+				WhereAny(StartingBetween(from, to)),
+			`{"query":"","page":{"current":1,"size":1000},"filters":{
+				"all":[{"can_book":["true"]}],
+				"any":[{"start_time_utc":{"from":"2024-03-05T12:00:00Z","to":"2024-03-06T12:00:00Z"}}]}}`,
+		},
+		{
+			"only empty filters",
+			NewQuery().Where(Centers(), Instructors()).WhereNone(Any(Centers())),
+			`{"query":"","page":{"current":1,"size":1000}}`,
+		},
+		{
+			"everything",
+			NewQuery().
+				Text("sculpt").
+				Where(
+					Centers("a", "b"),
+					Categories("Yoga Sculpt"),
+					Statuses(2),
+					Range(FieldAvailableSlots, 1, nil),
+				).
+				WhereAny(
+					All(StartingBetween(from, to), Centers()),
+					PriceBetween(0, 20),
+				).
+				WhereNone(Virtual(true), ClassTypes(3, 4)).
+				SortBy(FieldStartTime, "asc").
+				Fields(FieldCenterID, FieldSessionID).
+				GroupBy(FieldCenterID),
+			`{"query":"sculpt","page":{"current":1,"size":1000},
+				"sort":[{"start_time":"asc"}],
+				"filters":{
+					"all":[
+						{"center.id":["a","b"]},
+						{"class.category.name":["Yoga Sculpt"]},
+						{"status":[2]},
+						{"available_slots":{"from":1}}
+					],
+					"any":[
+						{"all":[{"start_time_utc":{"from":"2024-03-05T12:00:00Z","to":"2024-03-06T12:00:00Z"}}]},
+						{"price":{"from":0,"to":20}}
+					],
+					"none":[
+						{"is_virtual_class":["true"]},
+						{"class.type":[3,4]}
+					]
+				},
+				"result_fields":{"center.id":{"raw":{}},"session_id":{"raw":{}}},
+				"group":{"field":"center.id"}}`,
+		},
+	}
+	for _, test := range tests {
+		got, err := json.Marshal(test.query)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var want bytes.Buffer
+		if err := json.Compact(&want, []byte(test.want)); err != nil {
+			t.Fatalf("%s: invalid golden JSON: %v", test.name, err)
+		}
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("%s:\ngot  %s\nwant %s", test.name, got, want.Bytes())
+		}
+	}
+}
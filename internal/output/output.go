@@ -0,0 +1,148 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is how a command writes its results
+type Format string
+
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	CSV   Format = "csv"
+)
+
+// Parse checks a format given on the command line
+func Parse(s string) (Format, error) {
+	switch format := Format(strings.ToLower(s)); format {
+	case Table, JSON, YAML, CSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown output format %q, expected table, json, yaml or csv", s)
+}
+
+// Rows is the flat form of a result, used for table and CSV output. Header
+// names are snake_case; tables show them upper-cased.
+type Rows struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Write writes value in the given format. JSON and YAML encode value itself,
+// YAML using the JSON field names and order so both follow the same schema.
+// Tables and CSV write rows instead.
+func Write(w io.Writer, format Format, value any, rows Rows) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(value); err != nil {
+			return fmt.Errorf("error writing json: %v", err)
+		}
+		return nil
+	case YAML:
+		return writeYAML(w, value)
+	case CSV:
+		cw := csv.NewWriter(w)
+		cw.Write(rows.Header)
+		cw.WriteAll(rows.Rows)
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("error writing csv: %v", err)
+		}
+		return nil
+	case Table:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		header := make([]string, len(rows.Header))
+		for i, name := range rows.Header {
+			header[i] = strings.ToUpper(strings.ReplaceAll(name, "_", " "))
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows.Rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// writeYAML converts the JSON encoding of value into YAML, keeping the key
+// order of the JSON object instead of sorting keys
+func writeYAML(w io.Writer, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error writing yaml: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := yamlNode(dec)
+	if err != nil {
+		return fmt.Errorf("error writing yaml: %v", err)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return fmt.Errorf("error writing yaml: %v", err)
+	}
+	return enc.Close()
+}
+
+// yamlNode reads the next JSON value from dec as a YAML node
+func yamlNode(dec *json.Decoder) (*yaml.Node, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if token == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for dec.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, scalar("!!str", key.(string)))
+			}
+			child, err := yamlNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		// Closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return scalar("!!str", token), nil
+	case json.Number:
+		if strings.ContainsAny(token.String(), ".eE") {
+			return scalar("!!float", token.String()), nil
+		}
+		return scalar("!!int", token.String()), nil
+	case bool:
+		return scalar("!!bool", fmt.Sprint(token)), nil
+	default:
+		return scalar("!!null", "null"), nil
+	}
+}
+
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+type class struct {
+	Name      string   `json:"name"`
+	Slots     int      `json:"slots"`
+	Score     float64  `json:"score"`
+	Open      bool     `json:"open"`
+	Tags      []string `json:"tags"`
+	Zone      *string  `json:"zone"`
+	StartTime string   `json:"start_time"`
+}
+
+var (
+	classes = []class{
+		{Name: "Yoga Sculpt", Slots: 3, Score: 1.5, Open: true, Tags: []string{"hot"}, StartTime: "2024-03-05T06:00:00Z"},
+		{Name: "C2, \"Heated\"", Slots: 0, Score: 2, Tags: []string{}, StartTime: "2024-03-05T07:00:00Z"},
+	}
+	rows = Rows{
+		Header: []string{"name", "slots", "start_time"},
+		Rows: [][]string{
+			{"Yoga Sculpt", "3", "2024-03-05T06:00:00Z"},
+			{"C2, \"Heated\"", "0", "2024-03-05T07:00:00Z"},
+		},
+	}
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{YAML, `- name: Yoga Sculpt
+  slots: 3
+  score: 1.5
+  open: true
+  tags:
+    - hot
+  zone: null
+  start_time: "2024-03-05T06:00:00Z"
+- name: C2, "Heated"
+  slots: 0
+  score: 2
+  open: false
+  tags: []
+  zone: null
+  start_time: "2024-03-05T07:00:00Z"
+`},
+		{CSV, `name,slots,start_time
+Yoga Sculpt,3,2024-03-05T06:00:00Z
+"C2, ""Heated""",0,2024-03-05T07:00:00Z
+`},
+		{Table, `NAME          SLOTS  START TIME
+Yoga Sculpt   3      2024-03-05T06:00:00Z
+C2, "Heated"  0      2024-03-05T07:00:00Z
+`},
+		{JSON, `[
+  {
+    "name": "Yoga Sculpt",
+    "slots": 3,
+    "score": 1.5,
+    "open": true,
+    "tags": [
+      "hot"
+    ],
+    "zone": null,
+    "start_time": "2024-03-05T06:00:00Z"
+  },
+  {
+    "name": "C2, \"Heated\"",
+    "slots": 0,
+    "score": 2,
+    "open": false,
+    "tags": [],
+    "zone": null,
+    "start_time": "2024-03-05T07:00:00Z"
+  }
+]
+`},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, test.format, classes, rows); err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s:\ngot\n%s\nwant\n%s", test.format, got, test.want)
+		}
+	}
+}
+
+func TestWriteEmptyYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, YAML, []class{}, Rows{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("got %q, want %q", got, "[]\n")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, value := range []string{"table", "JSON", "yaml", "csv"} {
+		if _, err := Parse(value); err != nil {
+			t.Errorf("Parse(%q): %v", value, err)
+		}
+	}
+	if _, err := Parse("xml"); err == nil {
+		t.Error("Parse(\"xml\") succeeded")
+	}
+}
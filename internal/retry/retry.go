@@ -0,0 +1,131 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/clock"
+)
+
+// Policy controls how many times an operation is attempted and how long to
+// wait between attempts
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Clock       clock.Clock // Used to wait between attempts, defaults to clock.System
+}
+
+// DefaultPolicy is used by the API clients when no policy is configured
+var DefaultPolicy = Policy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// Error marks an error as transient. RetryAfter is the delay requested by the
+// server, if any.
+type Error struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable wraps err so that Do will try the operation again
+func Retryable(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Err: err, RetryAfter: retryAfter}
+}
+
+// FromResponse wraps err as retryable when the response status indicates a
+// transient failure (429 or 5xx), honouring the Retry-After header
+func (p Policy) FromResponse(resp *http.Response, err error) error {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return Retryable(err, p.ParseRetryAfter(resp.Header.Get("Retry-After")))
+	}
+	return err
+}
+
+// ParseRetryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date, which is measured from the policy's clock. It returns 0 when the
+// header is missing or invalid.
+func (p Policy) ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(p.clock().Now()); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the context is
+// done or the policy runs out of attempts
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	attempts := max(p.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var retryErr *Error
+		if !errors.As(err, &retryErr) || attempt >= attempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay := p.Backoff(attempt)
+		if retryErr.RetryAfter > 0 {
+			delay = min(retryErr.RetryAfter, p.MaxDelay)
+		}
+		slog.WarnContext(ctx, "Retrying after transient error", "attempt", attempt, "delay", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-p.clock().After(delay):
+		}
+	}
+}
+
+// clock returns the policy's clock, defaulting to the system clock
+func (p Policy) clock() clock.Clock {
+	if p.Clock == nil {
+		return clock.System
+	}
+	return p.Clock
+}
+
+// Backoff returns the delay before the given retry using exponential backoff
+// with full jitter
+func (p Policy) Backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if shift := attempt - 1; shift < 32 {
+		delay = min(p.BaseDelay<<shift, p.MaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return rand.N(delay) + 1
+}
@@ -0,0 +1,208 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/clock"
+)
+
+var epoch = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// waitForSleep blocks until the code under test is waiting on the fake clock
+func waitForSleep(t *testing.T, fake *clock.Fake) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for fake.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a retry to sleep")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDo(t *testing.T) {
+	transient := Retryable(errors.New("unavailable"), 0)
+	permanent := errors.New("bad request")
+
+	tests := []struct {
+		name      string
+		errs      []error // Returned by successive attempts, nil after the last
+		wantErr   error
+		wantCalls int
+	}{
+		{"success", nil, nil, 1},
+		{"transient then success", []error{transient, transient}, nil, 3},
+		{"permanent", []error{permanent, transient}, permanent, 1},
+		{"transient then permanent", []error{transient, permanent}, permanent, 2},
+		{"out of attempts", []error{transient, transient, transient, transient}, transient, 3},
+	}
+	for _, test := range tests {
+		fake := clock.NewFake(epoch)
+		policy := Policy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 4 * time.Second, Clock: fake}
+
+		calls := 0
+		done := make(chan error, 1)
+		go func() {
+			done <- policy.Do(context.Background(), func(context.Context) error {
+				calls++
+				if calls > len(test.errs) {
+					return nil
+				}
+				return test.errs[calls-1]
+			})
+		}()
+
+		var err error
+	wait:
+		for {
+			select {
+			case err = <-done:
+				break wait
+			default:
+			}
+			if fake.Waiters() > 0 {
+				fake.Advance(policy.MaxDelay)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if err != test.wantErr {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.wantErr)
+		}
+		if calls != test.wantCalls {
+			t.Errorf("%s: got %d calls, want %d", test.name, calls, test.wantCalls)
+		}
+	}
+}
+
+func TestDoRetryAfter(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	policy := Policy{MaxAttempts: 2, BaseDelay: time.Hour, MaxDelay: time.Hour, Clock: fake}
+
+	calls := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- policy.Do(context.Background(), func(context.Context) error {
+			calls++
+			if calls == 1 {
+				return Retryable(errors.New("slow down"), 3*time.Second)
+			}
+			return nil
+		})
+	}()
+
+	// The server's delay is used instead of the backoff
+	waitForSleep(t, fake)
+	fake.Advance(2 * time.Second)
+	if fake.Waiters() != 1 {
+		t.Fatal("retried before the Retry-After delay")
+	}
+	fake.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
+
+func TestDoContextCancelled(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	policy := Policy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Second, Clock: fake}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	transient := Retryable(errors.New("unavailable"), 0)
+	done := make(chan error, 1)
+	go func() {
+		done <- policy.Do(ctx, func(context.Context) error { return transient })
+	}()
+
+	waitForSleep(t, fake)
+	cancel()
+	if err := <-done; err != transient {
+		t.Errorf("got error %v, want %v", err, transient)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{40, time.Second},
+	}
+	for _, test := range tests {
+		for range 100 {
+			if d := policy.Backoff(test.attempt); d <= 0 || d > test.ceiling {
+				t.Fatalf("Backoff(%d) = %v, want in (0, %v]", test.attempt, d, test.ceiling)
+			}
+		}
+	}
+
+	if d := (Policy{}).Backoff(1); d != 0 {
+		t.Errorf("Backoff without delays = %v, want 0", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	policy := Policy{Clock: clock.NewFake(epoch)}
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"0", 0},
+		{"-5", 0},
+		{epoch.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{epoch.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, test := range tests {
+		if got := policy.ParseRetryAfter(test.value); got != test.want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestFromResponse(t *testing.T) {
+	policy := Policy{Clock: clock.NewFake(epoch)}
+	apiErr := errors.New("api error")
+	tests := []struct {
+		status     int
+		retryAfter string
+		retryable  bool
+		want       time.Duration
+	}{
+		{http.StatusServiceUnavailable, "", true, 0},
+		{http.StatusTooManyRequests, "7", true, 7 * time.Second},
+		{http.StatusBadRequest, "7", false, 0},
+		{http.StatusNotFound, "", false, 0},
+	}
+	for _, test := range tests {
+		resp := &http.Response{StatusCode: test.status, Header: http.Header{}}
+		if test.retryAfter != "" {
+			resp.Header.Set("Retry-After", test.retryAfter)
+		}
+		err := policy.FromResponse(resp, apiErr)
+		if !errors.Is(err, apiErr) {
+			t.Errorf("%d: got %v, want it to wrap the API error", test.status, err)
+		}
+		var retryErr *Error
+		if errors.As(err, &retryErr) != test.retryable {
+			t.Errorf("%d: retryable = %v, want %v", test.status, !test.retryable, test.retryable)
+			continue
+		}
+		if test.retryable && retryErr.RetryAfter != test.want {
+			t.Errorf("%d: got RetryAfter %v, want %v", test.status, retryErr.RetryAfter, test.want)
+		}
+	}
+}
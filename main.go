@@ -2,102 +2,87 @@ package main
 
 import (
 	"context"
-	"flag"
-	"log"
-	"time"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"github.com/eshaanm25/corepower/internal/cognito"
-	"github.com/eshaanm25/corepower/internal/corepower"
-	"github.com/eshaanm25/corepower/internal/opensearch"
+	"github.com/eshaanm25/corepower/internal/logging"
 )
 
-func main() {
-	// Define command line flags
-	username := flag.String("username", "", "CorePower username")
-	password := flag.String("password", "", "CorePower password")
-	flag.Parse()
-
-	if *username == "" || *password == "" {
-		log.Fatal("Both -username and -password flags are required")
-	}
+const usage = `Usage: corepower [command] [flags]
+
+Commands:
+  book       Search for classes and book the best match (default)
+  search     List the bookable classes in the booking horizon
+  plan       Show the classes book would try, best first, without booking
+  list       List upcoming reservations
+  serve      Serve an HTTP API to search, book and cancel for several accounts
+  watch      Stay running and book each day as soon as the booking window opens
+  centers    List studios, optionally filtered by name or distance
+  export     Export reservations, "export ics" for a calendar feed or
+             "export caldav" to sync them into a CalDAV calendar
+  collect    Record the availability of upcoming classes for stats
+  stats      Report how quickly classes fill, from collected snapshots
+  history    Show and export the record of past booking runs
+  notify     "notify test" renders sample notifications for the configured sinks
+
+Run "corepower <command> -h" for the flags of a command. Exit codes are
+listed in the README. search, plan, list
+and book take -output table|json|yaml|csv for scripts.
+`
 
-	// Get Central Time location
-	location, err := time.LoadLocation("America/Chicago")
+func main() {
+	// Logs go through slog, text by default or JSON for log pipelines, with
+	// tokens, passwords and emails redacted and an ID per run
+	opts, err := logging.OptionsFromEnv()
 	if err != nil {
-		log.Fatalf("Error loading timezone: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
 	}
+	opts.RunID = logging.NewID()
+	logging.Setup(opts)
 
-	// Search For Classes
+	// Cancel in-flight requests on Ctrl+C or when the runner stops the job
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Get start and end times
-	startTime := time.Now().UTC()
-	endTime := startTime.AddDate(0, 0, 14)
-
-	// Get center IDs
-	centerIds := []string{
-		"96efaaaa-b040-4d12-8829-51317dd8c1c2", // Cedar Park
-		"d810c305-2816-4a92-8c3d-47fe0a69d63a", // Monarch
-		"a9d80dd6-0610-4584-b945-f297122d6268", // Mueller
-		"5262c2d4-7f8f-4d15-9adf-5ba107409b30", // Triangle
+	// Without a command, flags go to book so existing invocations keep working
+	command, args := "book", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
 
-	// Initialize the search client
-	searchClient := &opensearch.Client{}
-	searchClient.Initialize()
-
-	// Call the search function
-	log.Println("Searching for available classes...")
-	res, err := searchClient.Search(startTime, endTime, centerIds)
-	if err != nil {
-		log.Fatalf("Error during search: %v", err)
-	}
-	log.Printf("Found %d classes...", len(res.Results))
-
-	// Find ideal class based on preferences
-	log.Println("Finding ideal class based on preferences...")
-	idealClass := corepower.FindIdealClass(res)
-	if idealClass == nil {
-		log.Println("No ideal class found matching preferences")
-		return
-	} else {
-		// Print ideal class details
-		log.Printf("Found ideal class: %s in %s at %s. The available slots are %.0f\n",
-			idealClass.ClassCategoryName,
-			idealClass.CenterName,
-			idealClass.StartTimeUtc.In(location).Format("Mon Jan 2 3:04 PM MST"),
-			idealClass.AvailableSlots)
-
-		// Reserve a Class
-		log.Println("Attempting to book the class...")
-
-		// Initialize the Cognito token manager with provided credentials
-		ctm := cognito.NewCognitoTokenManager(*username, *password)
-
-		// Authenticate to get the token
-		err = ctm.Authenticate(context.Background())
-		if err != nil {
-			log.Fatalf("Error authenticating with CorePower API: %v", err)
-		}
-		log.Println("Successfully authenticated with CorePower API")
-
-		// Initialize the Reservations Client
-		corePowerClient := &corepower.Client{
-			Token: ctm.GetIdToken(),
-		}
-		corePowerClient.Initialize()
-
-		// Reserve the class
-		log.Printf("Sending reservation request for %s at %s in %s...",
-			idealClass.ClassCategoryName,
-			idealClass.StartTimeUtc.In(location).Format("Mon Jan 2 3:04 PM MST"),
-			idealClass.CenterName)
-		response, err := corePowerClient.Reserve(idealClass.CenterID, idealClass.SessionID)
-		if err != nil {
-			log.Fatalf("Error reserving: %v\n", err)
-		} else {
-			log.Printf("Successfully booked: %s at %s in %s", response.ClassName, response.StartTimeUTC, response.Center)
-		}
-
-		log.Println("Reservation process completed")
+	switch command {
+	case "book":
+		runBook(ctx, args)
+	case "search":
+		runSearch(ctx, args)
+	case "plan":
+		runPlan(ctx, args)
+	case "list":
+		runList(ctx, args)
+	case "serve":
+		runServe(ctx, args)
+	case "watch":
+		runWatch(ctx, args)
+	case "centers":
+		runCenters(ctx, args)
+	case "export":
+		runExport(ctx, args)
+	case "collect":
+		runCollect(ctx, args)
+	case "stats":
+		runStats(args)
+	case "history":
+		runHistory(args)
+	case "notify":
+		runNotify(ctx, args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(exitUsage)
 	}
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/config"
+	"github.com/eshaanm25/corepower/internal/notify"
+)
+
+// runNotify works with the notification sinks from the config
+func runNotify(ctx context.Context, args []string) {
+	if len(args) == 0 || args[0] != "test" {
+		fmt.Fprintln(os.Stderr, "Usage: corepower notify test [flags]")
+		os.Exit(exitUsage)
+	}
+	runNotifyTest(ctx, args[1:])
+}
+
+// runNotifyTest renders sample events through every configured sink, and
+// optionally sends them
+func runNotifyTest(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("notify test", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to a JSON config file with a notify section")
+	event := flags.String("event", "", "Only render this event type: booked, waitlisted, failed or no_match")
+	send := flags.Bool("send", false, "Send the sample notifications instead of printing them")
+	flags.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exit(exitConfig, "Error loading config", "error", err)
+	}
+	if len(cfg.Notify) == 0 {
+		exit(exitConfig, "The config has no notify section")
+	}
+
+	var samples []notify.Event
+	for _, sample := range notify.Samples(clock.System.Now()) {
+		if *event == "" || string(sample.Type) == *event {
+			samples = append(samples, sample)
+		}
+	}
+	if len(samples) == 0 {
+		exit(exitUsage, "Unknown event type", "value", *event)
+	}
+
+	if *send {
+		notifier, err := notify.New(cfg.Notify)
+		if err != nil {
+			exit(exitConfig, "Error loading config", "error", err)
+		}
+		for _, sample := range samples {
+			if err := notifier.Notify(ctx, sample); err != nil {
+				fatal("Error sending notification", "event", sample.Type, "error", err)
+			}
+			slog.Info("Sent notification", "event", sample.Type)
+		}
+		return
+	}
+
+	for i, sink := range cfg.Notify {
+		for _, sample := range samples {
+			preview, err := notify.Preview(sink, sample)
+			if err != nil {
+				fatal("Error rendering notification", "event", sample.Type, "sink", sink.Type, "error", err)
+			}
+			fmt.Printf("=== %d: %s, %s ===\n%s\n\n", i+1, sink.Type, sample.Type, preview)
+		}
+	}
+}
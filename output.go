@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/centers"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/history"
+	"github.com/eshaanm25/corepower/internal/output"
+)
+
+// resultRows flattens classes for table and CSV output
+func resultRows(results []corepower.Result) output.Rows {
+	rows := output.Rows{Header: []string{
+		"start_time", "class", "center", "available_slots", "preference", "distance_miles", "score", "center_id", "session_id",
+	}}
+	for _, result := range results {
+		rows.Rows = append(rows.Rows, []string{
+			result.StartTime.Format(time.RFC3339),
+			result.ClassCategoryName,
+			result.CenterName,
+			strconv.FormatFloat(float64(result.AvailableSlots), 'f', -1, 32),
+			strconv.Itoa(result.Preference),
+			strconv.FormatFloat(result.DistanceMiles, 'f', 1, 64),
+			strconv.FormatFloat(result.Score, 'f', 2, 64),
+			result.CenterID,
+			strconv.FormatInt(result.SessionID, 10),
+		})
+	}
+	return rows
+}
+
+// reservationRows flattens reservations for table and CSV output
+func reservationRows(reservations []corepower.ReservationResponse) output.Rows {
+	rows := output.Rows{Header: []string{
+		"id", "start_time", "class", "center", "instructor", "status", "waitlisted", "session_id",
+	}}
+	for _, reservation := range reservations {
+		rows.Rows = append(rows.Rows, []string{
+			strconv.Itoa(reservation.ID),
+			reservation.StartTime,
+			reservation.ClassName,
+			reservation.Center,
+			reservation.Instructor,
+			reservation.Status,
+			strconv.FormatBool(reservation.Waitlisted()),
+			strconv.FormatInt(reservation.SessionID, 10),
+		})
+	}
+	return rows
+}
+
+// runRows flattens booking runs for table and CSV output, with the chosen
+// class in columns of its own
+func runRows(runs []history.Run) output.Rows {
+	rows := output.Rows{Header: []string{
+		"id", "time", "user", "outcome", "results", "candidates", "attempts",
+		"center", "class", "start_time", "session_id", "reservation_id", "error",
+	}}
+	for _, run := range runs {
+		row := []string{
+			run.ID, run.Time.Format(time.RFC3339), run.User, string(run.Outcome),
+			"", "", strconv.Itoa(len(run.Attempts)),
+			"", "", "", "", "", run.Error,
+		}
+		if run.Search != nil {
+			row[4] = strconv.Itoa(run.Search.Results)
+			row[5] = strconv.Itoa(run.Search.Candidates)
+		}
+		if run.Class != nil {
+			row[7] = run.Class.CenterName
+			row[8] = run.Class.ClassCategoryName
+			row[9] = run.Class.StartTime.Format(time.RFC3339)
+			row[10] = strconv.FormatInt(run.Class.SessionID, 10)
+		}
+		if run.Reservation != nil {
+			row[11] = strconv.Itoa(run.Reservation.ID)
+		}
+		rows.Rows = append(rows.Rows, row)
+	}
+	return rows
+}
+
+// statRow is one group of fill statistics, as written by stats -output
+type statRow struct {
+	By                      string  `json:"by"`
+	Key                     string  `json:"key"`
+	Sessions                int     `json:"sessions"`
+	Filled                  int     `json:"filled"`
+	FillRate                float64 `json:"fill_rate"`
+	MedianTimeToFullSeconds float64 `json:"median_time_to_full_seconds,omitempty"`
+	MedianLeadTimeSeconds   float64 `json:"median_lead_time_seconds,omitempty"`
+}
+
+// statRows flattens fill statistics for table and CSV output
+func statRows(stats []statRow) output.Rows {
+	rows := output.Rows{Header: []string{
+		"by", "key", "sessions", "filled", "fill_rate", "median_time_to_full_seconds", "median_lead_time_seconds",
+	}}
+	for _, s := range stats {
+		rows.Rows = append(rows.Rows, []string{
+			s.By,
+			s.Key,
+			strconv.Itoa(s.Sessions),
+			strconv.Itoa(s.Filled),
+			strconv.FormatFloat(s.FillRate, 'f', 3, 64),
+			strconv.FormatFloat(s.MedianTimeToFullSeconds, 'f', -1, 64),
+			strconv.FormatFloat(s.MedianLeadTimeSeconds, 'f', -1, 64),
+		})
+	}
+	return rows
+}
+
+// centerRow is a studio as written by centers -output
+type centerRow struct {
+	centers.Center
+	DistanceMiles *float64 `json:"distance_miles,omitempty"` // Set when searching around a point
+}
+
+// centerRows flattens studios for table and CSV output
+func centerRows(list []centerRow) output.Rows {
+	rows := output.Rows{Header: []string{"id", "name", "latitude", "longitude", "distance_miles"}}
+	for _, center := range list {
+		distance := "-"
+		if center.DistanceMiles != nil {
+			distance = strconv.FormatFloat(*center.DistanceMiles, 'f', 1, 64)
+		}
+		rows.Rows = append(rows.Rows, []string{
+			center.ID,
+			center.Name,
+			strconv.FormatFloat(center.Location.Latitude, 'f', 4, 64),
+			strconv.FormatFloat(center.Location.Longitude, 'f', 4, 64),
+			distance,
+		})
+	}
+	return rows
+}
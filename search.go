@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/eshaanm25/corepower/internal/cognito"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/output"
+)
+
+// runSearch lists the bookable classes of the profile's category within the
+// booking horizon, without filtering them by preference or ranking them
+func runSearch(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to a JSON config file")
+	outputFlag := flags.String("output", "table", "Output format: table, json, yaml or csv")
+	flags.Parse(args)
+
+	format, err := output.Parse(*outputFlag)
+	if err != nil {
+		exit(exitUsage, "Invalid -output", "error", err)
+	}
+	b, err := newBooker(*configPath, "", "")
+	if err != nil {
+		exit(exitConfig, "Error loading config", "error", err)
+	}
+
+	results, err := b.search(ctx)
+	if err != nil {
+		fatal("Error during search", "error", err)
+	}
+	if err := output.Write(os.Stdout, format, results, resultRows(results)); err != nil {
+		fatal("Error writing output", "error", err)
+	}
+}
+
+// search returns the bookable classes of the profile's category within the
+// booking horizon, in search order, whatever the preferences
+func (b *booker) search(ctx context.Context) ([]corepower.Result, error) {
+	profile := b.cfg.Profile
+	startTime, endTime := profile.Horizon.Window(b.clk.Now())
+	centerIds, err := resolveCenters(ctx, b.searchClient, b.cfg.CenterRefs())
+	if err != nil {
+		return nil, fmt.Errorf("error resolving centers: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Searching for available classes", "from", startTime, "to", endTime, "centers", len(centerIds))
+	res, err := b.searchClient.Find(ctx, profile.HorizonQuery(startTime, endTime, centerIds))
+	if err != nil {
+		return nil, fmt.Errorf("error during search: %w", err)
+	}
+
+	results := []corepower.Result{}
+	for _, class := range res.Results {
+		results = append(results, corepower.NewResult(class, profile))
+	}
+	return results, nil
+}
+
+// runPlan shows the classes book would try, best first, without booking
+func runPlan(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to a JSON config file")
+	outputFlag := flags.String("output", "table", "Output format: table, json, yaml or csv")
+	flags.Parse(args)
+
+	format, err := output.Parse(*outputFlag)
+	if err != nil {
+		exit(exitUsage, "Invalid -output", "error", err)
+	}
+	b, err := newBooker(*configPath, "", "")
+	if err != nil {
+		exit(exitConfig, "Error loading config", "error", err)
+	}
+
+	_, candidates, err := b.plan(ctx)
+	if err != nil {
+		fatal("Error planning run", "error", err)
+	}
+	if candidates == nil {
+		candidates = []corepower.Result{}
+	}
+	if err := output.Write(os.Stdout, format, candidates, resultRows(candidates)); err != nil {
+		fatal("Error writing output", "error", err)
+	}
+}
+
+// runList lists the account's upcoming reservations
+func runList(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	username := flags.String("username", "", "CorePower username")
+	password := flags.String("password", "", "CorePower password")
+	outputFlag := flags.String("output", "table", "Output format: table, json, yaml or csv")
+	flags.Parse(args)
+
+	if *username == "" || *password == "" {
+		exit(exitUsage, "Both -username and -password flags are required")
+	}
+	format, err := output.Parse(*outputFlag)
+	if err != nil {
+		exit(exitUsage, "Invalid -output", "error", err)
+	}
+
+	corePowerClient, err := newCorePowerClient(ctx, cognito.NewCognitoTokenManager(*username, *password))
+	if err != nil {
+		fatal("Error listing reservations", "error", err)
+	}
+	reservations, err := corePowerClient.Reservations(ctx)
+	if err != nil {
+		fatal("Error listing reservations", "error", err)
+	}
+	if reservations == nil {
+		reservations = []corepower.ReservationResponse{}
+	}
+	if err := output.Write(os.Stdout, format, reservations, reservationRows(reservations)); err != nil {
+		fatal("Error writing output", "error", err)
+	}
+}
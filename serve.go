@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/cognito"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/history"
+	"github.com/eshaanm25/corepower/internal/logging"
+)
+
+// runServe serves a JSON API to search, book and manage reservations for the
+// accounts in the config's serve section
+func runServe(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to a JSON config file with a serve section")
+	listen := flags.String("listen", "127.0.0.1:8080", "Address to serve the API on")
+	metricsAddr := flags.String("metrics", "", "Serve Prometheus metrics on this address, e.g. :9090")
+	maxAttempts := flags.Int("max-attempts", 3, "Maximum number of classes to try booking")
+	historyPath := flags.String("history", defaultHistoryPath(), "File to record runs in, empty to disable")
+	flags.Parse(args)
+
+	if *configPath == "" {
+		exit(exitUsage, "The -config flag is required")
+	}
+	if *maxAttempts < 1 {
+		exit(exitUsage, "The -max-attempts flag must be at least 1")
+	}
+	base, err := newBooker(*configPath, "", "")
+	if err != nil {
+		exit(exitConfig, "Error loading config", "error", err)
+	}
+	if base.cfg.Serve == nil || len(base.cfg.Serve.Users) == 0 {
+		exit(exitConfig, "The config has no serve users")
+	}
+
+	// Every user gets a booker of their own so tokens are kept per account,
+	// the config, notifier and search client are shared
+	a := &api{store: openHistory(*historyPath)}
+	for _, user := range base.cfg.Serve.Users {
+		b := *base
+		b.username = user.Username
+		b.maxAttempts = *maxAttempts
+		b.history = a.store
+		b.ctm = cognito.NewCognitoTokenManager(user.Username, user.Password)
+		a.users = append(a.users, &apiUser{key: user.APIKey, booker: &b})
+	}
+
+	if *metricsAddr != "" {
+		go serveMetrics(ctx, *metricsAddr)
+	}
+
+	server := &http.Server{Addr: *listen, Handler: a.handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Serving API", "url", "http://"+*listen+"/v1", "users", len(a.users))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("Error serving API", "error", err)
+	}
+}
+
+// api handles the HTTP API. Requests are authenticated with a per-user API
+// key and act for that user's CorePower account.
+type api struct {
+	users []*apiUser
+	store *history.Store // Shared with every user's booker, nil when runs are not recorded
+}
+
+// apiUser is an account reachable through the API
+type apiUser struct {
+	key    string
+	booker *booker
+
+	// Serializes booking runs, reservations and cancellations so that two
+	// requests do not book the same account at once
+	mu sync.Mutex
+}
+
+func (a *api) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/search", a.authed(a.search))
+	mux.HandleFunc("GET /v1/plan", a.authed(a.plan))
+	mux.HandleFunc("POST /v1/book", a.authed(a.book))
+	mux.HandleFunc("GET /v1/reservations", a.authed(a.reservations))
+	mux.HandleFunc("POST /v1/reservations", a.authed(a.reserve))
+	mux.HandleFunc("DELETE /v1/reservations/{id}", a.authed(a.cancel))
+	mux.HandleFunc("GET /v1/history", a.authed(a.history))
+	return mux
+}
+
+// authed resolves the API key of a request, given as a bearer token or in
+// X-API-Key, to its user
+func (a *api) authed(handle func(w http.ResponseWriter, r *http.Request, user *apiUser)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			key = bearer
+		}
+
+		var user *apiUser
+		for _, candidate := range a.users {
+			// Compare every key in constant time so timing reveals nothing
+			if subtle.ConstantTimeCompare([]byte(key), []byte(candidate.key)) == 1 {
+				user = candidate
+			}
+		}
+		if key == "" || user == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, "missing or unknown API key")
+			return
+		}
+
+		ctx := logging.WithRunID(r.Context(), logging.NewID())
+		slog.InfoContext(ctx, "API request", "method", r.Method, "path", r.URL.Path, "user", user.booker.username)
+		handle(w, r.WithContext(ctx), user)
+	}
+}
+
+// search handles GET /v1/search, the bookable classes of the profile's
+// category in the horizon, whether or not they match a preference
+func (a *api) search(w http.ResponseWriter, r *http.Request, user *apiUser) {
+	results, err := user.booker.search(r.Context())
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// plan handles GET /v1/plan, the classes a booking run would try, best first
+func (a *api) plan(w http.ResponseWriter, r *http.Request, user *apiUser) {
+	_, candidates, err := user.booker.plan(r.Context())
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if candidates == nil {
+		candidates = []corepower.Result{}
+	}
+	writeJSON(w, http.StatusOK, candidates)
+}
+
+// book handles POST /v1/book, a booking run like the book command. The run is
+// returned as recorded in the history, with an error status when it failed.
+func (a *api) book(w http.ResponseWriter, r *http.Request, user *apiUser) {
+	user.mu.Lock()
+	defer user.mu.Unlock()
+
+	// Finish the run even if the client goes away, it may already have
+	// reserved a class
+	run, err := user.booker.book(context.WithoutCancel(r.Context()), time.Time{})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Booking run failed", "error", err)
+		writeJSON(w, apiStatus(err), run)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// reservations handles GET /v1/reservations, the upcoming reservations
+func (a *api) reservations(w http.ResponseWriter, r *http.Request, user *apiUser) {
+	corePowerClient, err := newCorePowerClient(r.Context(), user.booker.ctm)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	reservations, err := corePowerClient.Reservations(r.Context())
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if reservations == nil {
+		reservations = []corepower.ReservationResponse{}
+	}
+	writeJSON(w, http.StatusOK, reservations)
+}
+
+// reserveRequest is the body of POST /v1/reservations, the IDs of a class as
+// returned by search and plan
+type reserveRequest struct {
+	CenterID  string `json:"center_id"`
+	SessionID int64  `json:"session_id"`
+}
+
+// reserve handles POST /v1/reservations, booking the given class
+func (a *api) reserve(w http.ResponseWriter, r *http.Request, user *apiUser) {
+	var req reserveRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.CenterID == "" || req.SessionID == 0 {
+		writeAPIError(w, http.StatusBadRequest, "center_id and session_id are required")
+		return
+	}
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+
+	ctx := context.WithoutCancel(r.Context())
+	corePowerClient, err := newCorePowerClient(ctx, user.booker.ctm)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	reservation, err := corePowerClient.Reserve(ctx, req.CenterID, req.SessionID)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	slog.InfoContext(ctx, "Reserved class", "class", reservation.ClassName, "center", reservation.Center, "reservation_id", reservation.ID)
+	writeJSON(w, http.StatusCreated, reservation)
+}
+
+// cancel handles DELETE /v1/reservations/{id}
+func (a *api) cancel(w http.ResponseWriter, r *http.Request, user *apiUser) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid reservation ID")
+		return
+	}
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+
+	ctx := context.WithoutCancel(r.Context())
+	corePowerClient, err := newCorePowerClient(ctx, user.booker.ctm)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if err := corePowerClient.Cancel(ctx, id); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	slog.InfoContext(ctx, "Cancelled reservation", "reservation_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// history handles GET /v1/history, the user's recorded booking runs. The
+// query takes the filters of the history command: since, until, outcome,
+// center, class and weekday.
+func (a *api) history(w http.ResponseWriter, r *http.Request, user *apiUser) {
+	if a.store == nil {
+		writeAPIError(w, http.StatusNotFound, "history is not recorded")
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := historyFilter(query.Get("since"), query.Get("until"), query.Get("outcome"), query.Get("weekday"), time.Now())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.User = user.booker.username
+	filter.Center = query.Get("center")
+	filter.Class = query.Get("class")
+
+	runs, err := a.store.Runs(filter)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if runs == nil {
+		runs = []history.Run{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// apiStatus maps an error to a response status: 409 when CorePower refused
+// the reservation or cancellation, 404 for unknown reservations, 502 when
+// CorePower rejected the account or could not be reached
+func apiStatus(err error) int {
+	var apiErr *corepower.APIError
+	switch {
+	case errors.Is(err, corepower.ErrClassFull), errors.Is(err, corepower.ErrAlreadyReserved),
+		errors.Is(err, corepower.ErrOutsideBookingWindow), errors.Is(err, corepower.ErrNotBookable):
+		return http.StatusConflict
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return http.StatusNotFound
+	}
+	switch failureCode(err) {
+	case exitAuth, exitNetwork:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// writeFailure logs a failed request and responds with its error
+func writeFailure(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "API request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	writeAPIError(w, apiStatus(err), err.Error())
+}
+
+// writeAPIError responds with {"error": msg}
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(value)
+}
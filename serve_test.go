@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/cognito"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/history"
+	"github.com/eshaanm25/corepower/internal/retry"
+)
+
+// testAPI returns an API for two users sharing a history file
+func testAPI(t *testing.T) (*api, http.Handler) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := history.Open(path)
+	now := time.Now().UTC()
+	for i, user := range []string{"a@example.com", "b@example.com", "a@example.com"} {
+		run := history.Run{ID: fmt.Sprintf("run-%d", i), Time: now.Add(-time.Duration(3-i) * time.Hour), User: user, Outcome: history.Booked}
+		if err := store.Append(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := &api{
+		store: store,
+		users: []*apiUser{
+			{key: "key-a", booker: &booker{username: "a@example.com"}},
+			{key: "key-b", booker: &booker{username: "b@example.com"}},
+		},
+	}
+	return a, a.handler()
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	_, handler := testAPI(t)
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no key", "", "", http.StatusUnauthorized},
+		{"unknown bearer", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"empty bearer", "Authorization", "Bearer ", http.StatusUnauthorized},
+		{"basic auth", "Authorization", "Basic a2V5LWE6", http.StatusUnauthorized},
+		{"unknown X-API-Key", "X-API-Key", "key-c", http.StatusUnauthorized},
+		{"key prefix", "X-API-Key", "key-", http.StatusUnauthorized},
+		{"bearer", "Authorization", "Bearer key-a", http.StatusOK},
+		{"X-API-Key", "X-API-Key", "key-b", http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/v1/history", nil)
+		if test.header != "" {
+			req.Header.Set(test.header, test.value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.want {
+			t.Errorf("%s: status %d, want %d", test.name, rec.Code, test.want)
+		}
+		if test.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s: missing WWW-Authenticate", test.name)
+		}
+	}
+
+	// Every endpoint needs a key
+	for _, endpoint := range []string{"GET /v1/search", "GET /v1/plan", "POST /v1/book", "GET /v1/reservations", "POST /v1/reservations", "DELETE /v1/reservations/1", "GET /v1/history"} {
+		var method, path string
+		fmt.Sscan(endpoint, &method, &path)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s without a key: status %d, want 401", endpoint, rec.Code)
+		}
+	}
+}
+
+func TestAPIMetricsNotServed(t *testing.T) {
+	_, handler := testAPI(t)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /metrics: status %d, want 404", rec.Code)
+	}
+}
+
+func TestAPIHistoryPerUser(t *testing.T) {
+	_, handler := testAPI(t)
+
+	tests := []struct {
+		key   string
+		query string
+		want  []string
+	}{
+		{"key-a", "", []string{"run-0", "run-2"}},
+		{"key-b", "", []string{"run-1"}},
+		// Filters cannot widen the results to other users
+		{"key-b", "?user=a@example.com", []string{"run-1"}},
+		{"key-a", "?outcome=failed", []string{}},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/v1/history"+test.query, nil)
+		req.Header.Set("X-API-Key", test.key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s%s: status %d: %s", test.key, test.query, rec.Code, rec.Body)
+		}
+
+		var runs []history.Run
+		if err := json.NewDecoder(rec.Body).Decode(&runs); err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, run := range runs {
+			ids = append(ids, run.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(test.want) {
+			t.Errorf("%s%s: runs %v, want %v", test.key, test.query, ids, test.want)
+		}
+	}
+}
+
+func TestAPIHistoryErrors(t *testing.T) {
+	a, handler := testAPI(t)
+
+	req := httptest.NewRequest("GET", "/v1/history?since=someday", nil)
+	req.Header.Set("X-API-Key", "key-a")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid since: status %d, want 400", rec.Code)
+	}
+
+	a.store = nil
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("history disabled: status %d, want 404", rec.Code)
+	}
+}
+
+func TestAPIStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"class full", fmt.Errorf("error reserving: %w", &corepower.APIError{StatusCode: 400, Code: "CLASS_FULL"}), http.StatusConflict},
+		{"already reserved", &corepower.APIError{StatusCode: 400, Message: "You are already booked"}, http.StatusConflict},
+		{"outside window", &corepower.APIError{StatusCode: 400, Code: "OUTSIDE_BOOKING_WINDOW"}, http.StatusConflict},
+		{"not bookable", &corepower.APIError{StatusCode: 400, Code: "MEMBERSHIP_REQUIRED"}, http.StatusConflict},
+		{"booking run with a full class", noBookingError(2, 1, corepower.ErrNotBookable), http.StatusConflict},
+		{"unknown reservation", &corepower.APIError{StatusCode: 404}, http.StatusNotFound},
+		{"rejected token", &corepower.APIError{StatusCode: 401}, http.StatusBadGateway},
+		{"bad credentials", fmt.Errorf("error authenticating: %w", cognito.ErrNotAuthorized), http.StatusBadGateway},
+		{"unreachable", &net.OpError{Op: "dial", Err: errors.New("refused")}, http.StatusBadGateway},
+		{"retries exhausted", &retry.Error{Err: errors.New("503")}, http.StatusBadGateway},
+		{"server error", &corepower.APIError{StatusCode: 500}, http.StatusInternalServerError},
+		{"other", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		if got := apiStatus(test.err); got != test.want {
+			t.Errorf("%s: apiStatus(%v) = %d, want %d", test.name, test.err, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/config"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/history"
+	"github.com/eshaanm25/corepower/internal/metrics"
+	"github.com/eshaanm25/corepower/internal/opensearch"
+	"github.com/eshaanm25/corepower/internal/output"
+)
+
+// defaultSnapshotPath is the snapshot file used when -snapshots is not given
+func defaultSnapshotPath() string {
+	path, err := history.DefaultSnapshotPath()
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// loadPredictor learns from the snapshots named in the config, or from the
+// collect command's default file
+func loadPredictor(cfg history.PredictConfig) (*history.Predictor, error) {
+	path := cfg.Snapshots
+	if path == "" {
+		path = defaultSnapshotPath()
+	}
+	series, err := history.OpenSnapshots(path).Series(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Predicting attendance from snapshots", "sessions", len(series))
+	return history.NewPredictor(series, cfg), nil
+}
+
+// runCollect records the availability of upcoming classes, once or every
+// interval until stopped
+func runCollect(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("collect", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to a JSON config file")
+	snapshotPath := flags.String("snapshots", defaultSnapshotPath(), "File to record snapshots in")
+	interval := flags.Duration("interval", 0, "Collect again after this long until stopped, e.g. 10m; 0 collects once")
+	allCategories := flags.Bool("all", false, "Collect every class category instead of only the profile's")
+	metricsAddr := flags.String("metrics", "", "Serve Prometheus metrics on this address while running, e.g. :9090")
+	flags.Parse(args)
+
+	if *snapshotPath == "" {
+		exit(exitUsage, "The -snapshots flag is required")
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exit(exitConfig, "Error loading config", "error", err)
+	}
+	category := cfg.Profile.Category
+	if *allCategories {
+		category = ""
+	}
+
+	searchClient := &opensearch.Client{}
+	searchClient.Initialize()
+	centerIds, err := resolveCenters(ctx, searchClient, cfg.CenterRefs())
+	if err != nil {
+		fatal("Error resolving centers", "error", err)
+	}
+
+	if *metricsAddr != "" && *interval > 0 {
+		go serveMetrics(ctx, *metricsAddr)
+	}
+
+	store := history.OpenSnapshots(*snapshotPath)
+	clk := clock.System
+	for {
+		// Every class that can currently be booked, up to the end of the horizon
+		now := clk.Now()
+		query := history.SnapshotQuery(now, now.AddDate(0, 0, cfg.Profile.Horizon.MaxDays), centerIds, category)
+		res, err := searchClient.Find(ctx, query)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("Error during search", "error", err)
+		} else {
+			snapshots := history.Snapshots(res, now)
+			if err := store.Append(snapshots); err != nil {
+				fatal("Error recording snapshots", "error", err)
+			}
+			slog.Info("Recorded class snapshots", "snapshots", len(snapshots))
+		}
+
+		if *interval <= 0 {
+			return
+		}
+		next := now.Add(*interval)
+		if !sleepUntil(ctx, clk, next) {
+			return
+		}
+		metrics.SchedulerLag.Observe(clk.Now().Sub(next).Seconds())
+	}
+}
+
+// runStats reports how quickly classes fill from the recorded snapshots
+func runStats(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	snapshotPath := flags.String("snapshots", defaultSnapshotPath(), "File snapshots are recorded in")
+	since := flags.String("since", "", "Only classes starting after this date (2006-01-02) or this long ago (e.g. 720h)")
+	by := flags.String("by", "center,weekday,time,instructor", "Comma separated groupings: center, weekday, time, instructor")
+	outputFlag := flags.String("output", "table", "Output format: table, json, yaml or csv")
+	flags.Parse(args)
+
+	if *snapshotPath == "" {
+		exit(exitUsage, "The -snapshots flag is required")
+	}
+	format, err := output.Parse(*outputFlag)
+	if err != nil {
+		exit(exitUsage, "Invalid -output", "error", err)
+	}
+	from, err := parseSince(*since, clock.System.Now())
+	if err != nil {
+		exit(exitUsage, "Invalid -since", "error", err)
+	}
+
+	series, err := history.OpenSnapshots(*snapshotPath).Series(from, time.Time{})
+	if err != nil {
+		fatal("Error reading snapshots", "error", err)
+	}
+	fills := history.Fills(series)
+	if len(fills) == 0 && format == output.Table {
+		fmt.Println("No snapshots recorded yet, run \"corepower collect\" to record some")
+		return
+	}
+
+	groupings := map[string]func(history.Fill) string{
+		"center":     func(f history.Fill) string { return f.Latest.CenterName },
+		"weekday":    func(f history.Fill) string { return f.Latest.StartTime.Weekday().String() },
+		"time":       func(f history.Fill) string { return f.Latest.StartTime.Format("15:04") },
+		"instructor": func(f history.Fill) string { return f.Latest.Instructor },
+	}
+
+	groups := map[string][]history.FillStats{}
+	names := splitList(*by)
+	for _, name := range names {
+		key, ok := groupings[name]
+		if !ok {
+			exit(exitUsage, "Unknown grouping", "value", name)
+		}
+		stats := history.GroupFills(fills, key)
+		if name == "weekday" {
+			// Calendar order rather than alphabetical
+			slices.SortStableFunc(stats, func(a, b history.FillStats) int {
+				return cmp.Compare(weekdayIndex(a.Key), weekdayIndex(b.Key))
+			})
+		}
+		groups[name] = stats
+	}
+
+	if format != output.Table {
+		rows := []statRow{}
+		for _, name := range names {
+			for _, s := range groups[name] {
+				rows = append(rows, statRow{
+					By:                      name,
+					Key:                     s.Key,
+					Sessions:                s.Sessions,
+					Filled:                  s.Filled,
+					FillRate:                s.FillRate(),
+					MedianTimeToFullSeconds: s.MedianTimeToFull.Seconds(),
+					MedianLeadTimeSeconds:   s.MedianLeadTime.Seconds(),
+				})
+			}
+		}
+		if err := output.Write(os.Stdout, format, rows, statRows(rows)); err != nil {
+			fatal("Error writing output", "error", err)
+		}
+		return
+	}
+
+	// Tables are printed one per grouping
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\tSESSIONS\tFILLED\tMEDIAN TIME TO FULL\tMEDIAN FULL BEFORE START\n", headerName(name))
+		for _, s := range groups[name] {
+			fmt.Fprintf(w, "%s\t%d\t%d (%.0f%%)\t%s\t%s\n", orDash(s.Key), s.Sessions, s.Filled, 100*s.FillRate(),
+				formatStatDuration(s.MedianTimeToFull), formatStatDuration(s.MedianLeadTime))
+		}
+	}
+	w.Flush()
+}
+
+func headerName(name string) string {
+	if name == "time" {
+		return "TIME SLOT"
+	}
+	return strings.ToUpper(name)
+}
+
+// formatStatDuration prints durations in days, hours and minutes, or "-" for
+// none
+func formatStatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	d = d.Round(time.Minute)
+	days, hours, minutes := int(d/(24*time.Hour)), int(d/time.Hour)%24, int(d/time.Minute)%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+func weekdayIndex(name string) int {
+	day, _ := corepower.ParseWeekday(name)
+	return int(day)
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
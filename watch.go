@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/eshaanm25/corepower/internal/clock"
+	"github.com/eshaanm25/corepower/internal/corepower"
+	"github.com/eshaanm25/corepower/internal/metrics"
+)
+
+// runWatch stays running and books every day the moment the booking window
+// opens
+func runWatch(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	username := flags.String("username", "", "CorePower username")
+	password := flags.String("password", "", "CorePower password")
+	maxAttempts := flags.Int("max-attempts", 3, "Maximum number of classes to try booking")
+	configPath := flags.String("config", "", "Path to a JSON config file")
+	historyPath := flags.String("history", defaultHistoryPath(), "File to record runs in, empty to disable")
+	at := flags.String("at", "00:00", "Time the booking window opens each day, in the profile's timezone or the local one")
+	warmup := flags.Duration("warmup", 30*time.Second, "How long before the window opens to authenticate")
+	metricsAddr := flags.String("metrics", "", "Serve Prometheus metrics on this address, e.g. :9090")
+	flags.Parse(args)
+
+	if *username == "" || *password == "" {
+		exit(exitUsage, "Both -username and -password flags are required")
+	}
+	opensAt, err := time.Parse("15:04", *at)
+	if err != nil {
+		exit(exitUsage, "Invalid -at, expected HH:MM", "value", *at)
+	}
+
+	b, err := newBooker(*configPath, *username, *password)
+	if err != nil {
+		exit(exitConfig, "Error loading config", "error", err)
+	}
+	b.maxAttempts = *maxAttempts
+	b.history = openHistory(*historyPath)
+
+	if *metricsAddr != "" {
+		go serveMetrics(ctx, *metricsAddr)
+	}
+
+	location := b.cfg.Profile.Location
+	if location == nil {
+		location = time.Local
+	}
+	for {
+		opened := nextOccurrence(b.clk.Now().In(location), corepower.TimeOfDayOf(opensAt))
+		slog.Info("Waiting for the booking window", "opens", opened.Format("Mon Jan 2 3:04 PM MST"))
+
+		// Have a token ready so the reservation goes out right away
+		if !sleepUntil(ctx, b.clk, opened.Add(-*warmup)) {
+			return
+		}
+		if _, err := b.ctm.Token(ctx); err != nil {
+			slog.Error("Error authenticating ahead of the booking run", "error", err)
+		}
+
+		if !sleepUntil(ctx, b.clk, opened) {
+			return
+		}
+		metrics.SchedulerLag.Observe(b.clk.Now().Sub(opened).Seconds())
+
+		if _, err := b.book(ctx, opened); err != nil {
+			slog.Error("Booking run failed", "error", err)
+		}
+	}
+}
+
+// nextOccurrence returns the next time after now at the given time of day
+func nextOccurrence(now time.Time, at corepower.TimeOfDay) time.Time {
+	next := at.On(now)
+	if !next.After(now) {
+		next = at.On(now.AddDate(0, 0, 1))
+	}
+	return next
+}
+
+// sleepUntil waits for t on the clock, returning false if ctx is done first
+func sleepUntil(ctx context.Context, clk clock.Clock, t time.Time) bool {
+	d := t.Sub(clk.Now())
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-clk.After(d):
+		return true
+	}
+}
+
+// serveMetrics serves /metrics until ctx is done
+func serveMetrics(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Serving metrics", "url", "http://"+addr+"/metrics")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Error serving metrics", "error", err)
+	}
+}